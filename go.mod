@@ -1,3 +1,3 @@
 module git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x
 
-go 1.20
+go 1.21
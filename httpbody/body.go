@@ -7,6 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
 )
 
 // FromJSON takes in JSON serializable input and returns either io.ReadCloser or error if the
@@ -21,19 +24,61 @@ func FromJSON(input any) (io.ReadCloser, error) {
 	return io.NopCloser(bytes.NewReader(data)), nil
 }
 
+// BindOption configures BindJSON and BindValidJSON.
+type BindOption func(*bindConfig)
+
+type bindConfig struct {
+	maxBytes int64
+}
+
+// WithMaxBytes limits the size of the body to n bytes. Reading a larger body fails with
+// *http.MaxBytesError, the same error as returned by http.MaxBytesReader, which can be
+// detected using errors.As.
+func WithMaxBytes(n int64) BindOption {
+	return func(c *bindConfig) {
+		c.maxBytes = n
+	}
+}
+
 // BindJSON binds provided io.ReadCloser body to a T type or returns an error in case operation fails.
 //
 // Provided generic T type, should support json.Unmarshal.
 //
 // Doesn't close underlying io.ReadCloser.
-func BindJSON[T any](body io.ReadCloser) (zero T, err error) {
-	data, err := io.ReadAll(body)
+func BindJSON[T any](body io.ReadCloser, opts ...BindOption) (zero T, err error) {
+	cfg := &bindConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	var r io.Reader = body
+	if cfg.maxBytes > 0 {
+		r = io.LimitReader(body, cfg.maxBytes+1)
+	}
+	data, err := io.ReadAll(r)
 	if err != nil {
 		return zero, fmt.Errorf("reading body: %w", err)
 	}
+	if cfg.maxBytes > 0 && int64(len(data)) > cfg.maxBytes {
+		return zero, fmt.Errorf("reading body: %w", &http.MaxBytesError{Limit: cfg.maxBytes})
+	}
 	var t T
 	if err = json.Unmarshal(data, &t); err != nil {
 		return zero, fmt.Errorf("unmarshaling body: %w", err)
 	}
 	return t, err
 }
+
+// BindValidJSON binds provided io.ReadCloser body to a T type using BindJSON and validates it
+// using xvalidate.Validate. Invalid fields are returned as xvalidate.Errors.
+//
+// Doesn't close underlying io.ReadCloser.
+func BindValidJSON[T any](body io.ReadCloser, opts ...BindOption) (zero T, err error) {
+	t, err := BindJSON[T](body, opts...)
+	if err != nil {
+		return zero, err
+	}
+	if err = xvalidate.Validate(t); err != nil {
+		return zero, fmt.Errorf("validating body: %w", err)
+	}
+	return t, nil
+}
@@ -2,11 +2,15 @@ package httpbody
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
 )
 
 func Test_FromJSON(t *testing.T) {
@@ -116,3 +120,74 @@ func TestBindJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestBindValidJSON(t *testing.T) {
+	type payload struct {
+		Value string `json:"value" validate:"required,max=5"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		want       payload
+		wantErr    bool
+		wantFields xvalidate.Errors
+	}{
+		{
+			name: "should bind valid body",
+			body: `{"value":"1234"}`,
+			want: payload{Value: "1234"},
+		},
+		{
+			name:    "should return error if the body is malformed",
+			body:    `{"value":`,
+			wantErr: true,
+		},
+		{
+			name:       "should return validation errors",
+			body:       `{"value":"123456"}`,
+			wantErr:    true,
+			wantFields: xvalidate.Errors{{Field: "value", Rule: "max", Message: "must be at most 5 characters long"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindValidJSON[payload](io.NopCloser(strings.NewReader(tt.body)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindValidJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindValidJSON() = got %v, want %v", got, tt.want)
+			}
+			var fields xvalidate.Errors
+			if errors.As(err, &fields); !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("BindValidJSON() = errors got %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestBindJSON_MaxBytes(t *testing.T) {
+	type payload struct {
+		Value string `json:"value"`
+	}
+
+	t.Run("should bind body within limit", func(t *testing.T) {
+		got, err := BindJSON[payload](io.NopCloser(strings.NewReader(`{"value":"1234"}`)), WithMaxBytes(16))
+		if err != nil {
+			t.Fatalf("BindJSON() error = %v", err)
+		}
+		if got.Value != "1234" {
+			t.Errorf("BindJSON() = got %v, want %v", got.Value, "1234")
+		}
+	})
+
+	t.Run("should return max bytes error if body exceeds limit", func(t *testing.T) {
+		_, err := BindJSON[payload](io.NopCloser(strings.NewReader(`{"value":"12345"}`)), WithMaxBytes(16))
+
+		var mbe *http.MaxBytesError
+		if !errors.As(err, &mbe) || mbe.Limit != 16 {
+			t.Errorf("BindJSON() error = %v, want *http.MaxBytesError with limit 16", err)
+		}
+	})
+}
@@ -23,6 +23,21 @@ func ExampleBindJSON() {
 
 }
 
+func ExampleBindValidJSON() {
+	type payload struct {
+		ID    string `json:"id" validate:"required"`
+		Rooms int    `json:"rooms" validate:"min=1"`
+	}
+	httpBody := io.NopCloser(strings.NewReader(`{"rooms":0}`))
+
+	_, err := BindValidJSON[payload](httpBody)
+
+	fmt.Println(err)
+
+	// Output:
+	// validating body: id: is required; rooms: must be at least 1
+}
+
 func ExampleFromJSON() {
 	type payload struct {
 		ID string `json:"id"`
@@ -0,0 +1,246 @@
+package xhttp
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// maxFormMemory is the maximum memory used for the non-file parts of multipart forms.
+const maxFormMemory = 32 << 20
+
+// BindQuery returns a new T filled from the URL query parameters of the request r. T must be
+// a struct or a pointer to a struct, the fields tagged with `query:"name"` are filled, see
+// BindValues for the supported field types.
+//
+// Errors of the individual fields are returned as FieldError values joined using errors.Join,
+// they can be extracted with FieldErrors and are written as an HTTP 422 by WriteError.
+func BindQuery[T any](r *http.Request) (T, error) {
+	return BindValues[T](r.URL.Query(), "query")
+}
+
+// BindValidQuery returns a new T filled from the URL query parameters of the request r using
+// BindQuery and validated using xvalidate.Validate. The error paths use the query parameter names.
+func BindValidQuery[T any](r *http.Request) (zero T, err error) {
+	t, err := BindQuery[T](r)
+	if err != nil {
+		return zero, err
+	}
+	if err = xvalidate.Validate(t, xvalidate.WithNameTags("query")); err != nil {
+		return zero, err
+	}
+	return t, nil
+}
+
+// BindForm returns a new T filled from the form of the request r, i.e. the URL-encoded or
+// multipart body values and the URL query parameters, with the body values taking precedence.
+// The fields tagged with `form:"name"` are filled, see BindValues for the supported field types.
+//
+// Errors of the individual fields are returned as FieldError values joined using errors.Join.
+func BindForm[T any](r *http.Request) (zero T, err error) {
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "multipart/form-data" {
+		err = r.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return zero, ErrBadRequest("invalid form").Wrap(err)
+	}
+	return BindValues[T](r.Form, "form")
+}
+
+// BindValidForm returns a new T filled from the form of the request r using BindForm and
+// validated using xvalidate.Validate. The error paths use the form field names.
+func BindValidForm[T any](r *http.Request) (zero T, err error) {
+	t, err := BindForm[T](r)
+	if err != nil {
+		return zero, err
+	}
+	if err = xvalidate.Validate(t, xvalidate.WithNameTags("form")); err != nil {
+		return zero, err
+	}
+	return t, nil
+}
+
+// BindValues returns a new T filled from values. T must be a struct or a pointer to a struct,
+// the exported fields tagged with tag are filled from the values of the tag name, e.g.
+// `query:"price_min"`. Fields without the tag or without values are left untouched.
+//
+// Supported field types are strings, booleans, integers, floats, time.Duration, time.Time
+// (RFC 3339 or a date, e.g. 2006-01-02), types implementing encoding.TextUnmarshaler and
+// pointers to those. Slices receive all the values of the repeated keys, with comma-separated
+// values split into separate elements, e.g. "rooms=1,2&rooms=3". Empty values leave non-string
+// fields untouched.
+//
+// Errors of the individual fields are returned as FieldError values joined using errors.Join.
+func BindValues[T any](values url.Values, tag string) (zero T, err error) {
+	var dst T
+	if err = bindValues(reflect.ValueOf(&dst).Elem(), tag, func(name string) []string { return values[name] }); err != nil {
+		return zero, err
+	}
+	return dst, nil
+}
+
+// bindValues fills the exported fields of the struct dst tagged with tag using the values
+// returned by lookup for the tag name. Fields without the tag or without values are left untouched.
+//
+// Errors of the individual fields are returned as FieldError values joined using errors.Join.
+func bindValues(dst reflect.Value, tag string, lookup func(name string) []string) error {
+	for dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	if dst.Kind() != reflect.Struct {
+		return fmt.Errorf("binding %s values: unsupported type %s, want struct", tag, dst.Type())
+	}
+
+	var errs []error
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := field.Tag.Lookup(tag)
+		if !ok || name == "-" {
+			continue
+		}
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setValues(dst.Field(i), values); err != nil {
+			errs = append(errs, &FieldError{Field: name, Message: err.Error()})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setValues sets v to the parsed values. Slices receive all the values split on commas,
+// any other type receives the first one.
+func setValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) && v.Type().Elem().Kind() != reflect.Uint8 {
+		var items []string
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, value := range items {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	if values[0] == "" && !isString(v.Type()) {
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+// setValue sets v to the parsed value.
+func setValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		t, err := parseTime(value)
+		if err != nil {
+			return fmt.Errorf("invalid time value %q, want RFC 3339 date-time or date", value)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration value %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid value %q: %w", value, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean value %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer value %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer value %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number value %q", value)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseTime parses value as an RFC 3339 date-time or a date.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// isString reports whether typ is a string or a pointer to a string.
+func isString(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.String
+}
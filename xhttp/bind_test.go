@@ -0,0 +1,187 @@
+package xhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sortOrder string
+
+func (s *sortOrder) UnmarshalText(text []byte) error {
+	switch v := sortOrder(text); v {
+	case "asc", "desc":
+		*s = v
+		return nil
+	}
+	return errors.New("want asc or desc")
+}
+
+type searchQuery struct {
+	Query    string        `query:"q"`
+	PriceMin *float64      `query:"price_min"`
+	Rooms    []int         `query:"rooms"`
+	Sort     sortOrder     `query:"sort"`
+	Since    *time.Time    `query:"since"`
+	MaxAge   time.Duration `query:"max_age"`
+	Page     int           `query:"page"`
+	Ignored  string
+}
+
+func TestBindQuery(t *testing.T) {
+	since := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	priceMin := 1500.5
+
+	tests := []struct {
+		name    string
+		query   string
+		want    searchQuery
+		wantErr []*FieldError
+	}{
+		{
+			name:  "should bind all supported types",
+			query: "q=flat&price_min=1500.5&rooms=1,2&rooms=3&sort=desc&since=2023-05-01&max_age=1h30m&page=2&Ignored=x",
+			want: searchQuery{
+				Query:    "flat",
+				PriceMin: &priceMin,
+				Rooms:    []int{1, 2, 3},
+				Sort:     "desc",
+				Since:    &since,
+				MaxAge:   90 * time.Minute,
+				Page:     2,
+			},
+		},
+		{
+			name:  "should bind RFC 3339 time",
+			query: "since=2023-05-01T00:00:00Z",
+			want:  searchQuery{Since: &since},
+		},
+		{
+			name:  "should leave fields with empty values untouched",
+			query: "q=&price_min=&rooms=&page=",
+			want:  searchQuery{},
+		},
+		{
+			name:  "should return errors of all invalid fields",
+			query: "price_min=cheap&rooms=1,two&sort=random&since=yesterday&max_age=long",
+			wantErr: []*FieldError{
+				{Field: "price_min", Message: `invalid number value "cheap"`},
+				{Field: "rooms", Message: `invalid integer value "two"`},
+				{Field: "sort", Message: `invalid value "random": want asc or desc`},
+				{Field: "since", Message: `invalid time value "yesterday", want RFC 3339 date-time or date`},
+				{Field: "max_age", Message: `invalid duration value "long"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/search?"+tt.query, nil)
+			got, err := BindQuery[searchQuery](r)
+			if tt.wantErr != nil {
+				if gotErr := FieldErrors(err); !reflect.DeepEqual(gotErr, tt.wantErr) {
+					t.Errorf("BindQuery() = errors got %v, want %v", gotErr, tt.wantErr)
+				}
+				if status := StatusCode(err); status != http.StatusUnprocessableEntity {
+					t.Errorf("StatusCode() = got %d, want %d", status, http.StatusUnprocessableEntity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BindQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindQuery() = got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	type form struct {
+		Title string   `form:"title"`
+		Rooms int      `form:"rooms"`
+		Tags  []string `form:"tag"`
+	}
+
+	t.Run("should bind url-encoded body and query", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/listings?rooms=2&title=query", strings.NewReader("title=flat&tag=a&tag=b"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		got, err := BindForm[*form](r)
+		if err != nil {
+			t.Fatalf("BindForm() error = %v", err)
+		}
+		if want := (&form{Title: "flat", Rooms: 2, Tags: []string{"a", "b"}}); !reflect.DeepEqual(got, want) {
+			t.Errorf("BindForm() = got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("should bind multipart body", func(t *testing.T) {
+		body := "--boundary\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nflat\r\n--boundary--\r\n"
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(body))
+		r.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+
+		got, err := BindForm[form](r)
+		if err != nil {
+			t.Fatalf("BindForm() error = %v", err)
+		}
+		if got.Title != "flat" {
+			t.Errorf("BindForm() = title got %q, want %q", got.Title, "flat")
+		}
+	})
+
+	t.Run("should return bad request for malformed body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader("title=%zz"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		_, err := BindForm[form](r)
+		if status := StatusCode(err); status != http.StatusBadRequest {
+			t.Errorf("BindForm() = status got %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestBindValues(t *testing.T) {
+	t.Run("should reject non-struct types", func(t *testing.T) {
+		if _, err := BindValues[string](url.Values{}, "query"); err == nil {
+			t.Errorf("BindValues() = expected error for non-struct type")
+		}
+	})
+}
+
+func TestBindValidQuery(t *testing.T) {
+	type query struct {
+		PriceMin int    `query:"price_min" validate:"min=0"`
+		Sort     string `query:"sort" validate:"omitempty,oneof=asc desc"`
+	}
+
+	t.Run("should bind valid query", func(t *testing.T) {
+		got, err := BindValidQuery[query](httptest.NewRequest(http.MethodGet, "/search?price_min=10&sort=asc", nil))
+		if err != nil {
+			t.Fatalf("BindValidQuery() error = %v", err)
+		}
+		if want := (query{PriceMin: 10, Sort: "asc"}); got != want {
+			t.Errorf("BindValidQuery() = got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("should return validation errors using query names", func(t *testing.T) {
+		_, err := BindValidQuery[query](httptest.NewRequest(http.MethodGet, "/search?price_min=-1&sort=random", nil))
+
+		want := []*FieldError{
+			{Field: "price_min", Message: "must be at least 0"},
+			{Field: "sort", Message: "must be one of: asc, desc"},
+		}
+		if got := FieldErrors(err); !reflect.DeepEqual(got, want) {
+			t.Errorf("BindValidQuery() = errors got %v, want %v", got, want)
+		}
+		if status := StatusCode(err); status != http.StatusUnprocessableEntity {
+			t.Errorf("StatusCode() = got %d, want %d", status, http.StatusUnprocessableEntity)
+		}
+	})
+}
@@ -0,0 +1,366 @@
+package xhttp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit of BreakerTransport.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the requests through and records their outcomes.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails the requests fast with *CircuitOpenError.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of the probe requests through to decide whether
+	// to close or to open the circuit again.
+	BreakerHalfOpen
+)
+
+// String implements fmt.Stringer interface.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// CircuitOpenError is returned by BreakerTransport for the requests to the hosts with an
+// open circuit. StatusCode maps it to HTTP 503 StatusServiceUnavailable.
+type CircuitOpenError struct {
+	// Host is the host of the request.
+	Host string
+	// RetryAfter is the time after which the circuit lets the probe requests through.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker of %s is open", e.Host)
+}
+
+// BreakerOption configures BreakerTransport.
+type BreakerOption func(*BreakerTransport)
+
+// WithBreakerWindow sets the duration of the rolling window the outcomes of the requests
+// are recorded in, defaults to 10s.
+func WithBreakerWindow(d time.Duration) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.window = d
+	}
+}
+
+// WithBreakerThreshold sets the rate of the failed requests in the window that opens the
+// circuit, defaults to 0.5, once there were at least minRequests in the window, defaults to 20.
+func WithBreakerThreshold(errorRate float64, minRequests int) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.errorRate = errorRate
+		t.minRequests = minRequests
+	}
+}
+
+// WithBreakerSlowCalls sets the duration after which the requests are considered slow and
+// the rate of the slow requests in the window that opens the circuit. By default the latency
+// is not taken into account.
+func WithBreakerSlowCalls(threshold time.Duration, rate float64) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.slowThreshold = threshold
+		t.slowRate = rate
+	}
+}
+
+// WithBreakerOpenTimeout sets the time the circuit stays open before letting the probe
+// requests through, defaults to 30s.
+func WithBreakerOpenTimeout(d time.Duration) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.openTimeout = d
+	}
+}
+
+// WithBreakerProbes sets the number of the successful probe requests that close the
+// half-open circuit, defaults to 1. A single failed probe opens the circuit again.
+func WithBreakerProbes(n int) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.probes = n
+	}
+}
+
+// WithBreakerFailure sets the function that reports whether the request has failed, defaults
+// to the requests failed with an error or a 5xx status code.
+func WithBreakerFailure(failed func(res *http.Response, err error) bool) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.failed = failed
+	}
+}
+
+// WithBreakerStateHook sets the function called when the circuit of a host changes the
+// state, e.g. to log it or to record a metric.
+func WithBreakerStateHook(hook func(host string, from, to BreakerState)) BreakerOption {
+	return func(t *BreakerTransport) {
+		t.hook = hook
+	}
+}
+
+// BreakerTransport is an http.RoundTripper implementing the circuit breaker pattern, so that
+// the callers of a degraded service fail fast instead of piling up waiting on it.
+//
+// Every host has its own circuit. A closed circuit records the outcomes and the latency of
+// the requests in a rolling window and opens when the rate of the failed or slow requests
+// exceeds the threshold. An open circuit fails the requests with *CircuitOpenError without
+// sending them, and becomes half-open after the open timeout. A half-open circuit lets the
+// probe requests through, closing once they succeed or opening again if any of them fails.
+//
+// The requests canceled by the caller are not recorded. Compose it with RetryTransport
+// as the inner transport, so that every retry is recorded and the open circuit is not retried:
+//
+//	NewRetryTransport(NewBreakerTransport(http.DefaultTransport))
+type BreakerTransport struct {
+	next          http.RoundTripper
+	window        time.Duration
+	errorRate     float64
+	minRequests   int
+	slowThreshold time.Duration
+	slowRate      float64
+	openTimeout   time.Duration
+	probes        int
+	failed        func(res *http.Response, err error) bool
+	hook          func(host string, from, to BreakerState)
+	now           func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewBreakerTransport returns a new BreakerTransport sending the requests using next,
+// http.DefaultTransport if nil.
+func NewBreakerTransport(next http.RoundTripper, opts ...BreakerOption) *BreakerTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &BreakerTransport{
+		next:        next,
+		window:      10 * time.Second,
+		errorRate:   0.5,
+		minRequests: 20,
+		openTimeout: 30 * time.Second,
+		probes:      1,
+		failed: func(res *http.Response, err error) bool {
+			return err != nil || res.StatusCode >= http.StatusInternalServerError
+		},
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// State returns the state of the circuit of the host.
+func (t *BreakerTransport) State(host string) BreakerState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.circuits[host]; ok {
+		return c.state
+	}
+	return BreakerClosed
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	gen, err := t.acquire(host)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	start := t.now()
+	res, err := t.next.RoundTrip(req)
+	o := outcome{
+		ignored: req.Context().Err() != nil,
+		failed:  t.failed(res, err),
+		slow:    t.slowThreshold > 0 && t.now().Sub(start) >= t.slowThreshold,
+	}
+	t.release(host, gen, o)
+	return res, err
+}
+
+// acquire checks whether the request to the host is allowed and returns the generation of
+// the circuit, i.e. the number of its state changes.
+func (t *BreakerTransport) acquire(host string) (uint64, error) {
+	t.mu.Lock()
+	c, ok := t.circuits[host]
+	if !ok {
+		c = &circuit{buckets: newRollingWindow(t.window, 10)}
+		t.circuits[host] = c
+	}
+	now := t.now()
+	from := c.state
+	if c.state == BreakerOpen && !now.Before(c.openedAt.Add(t.openTimeout)) {
+		c.transition(BreakerHalfOpen, now)
+	}
+	var err error
+	switch c.state {
+	case BreakerOpen:
+		err = &CircuitOpenError{Host: host, RetryAfter: c.openedAt.Add(t.openTimeout).Sub(now)}
+	case BreakerHalfOpen:
+		if c.inFlight >= t.probes-c.successes {
+			err = &CircuitOpenError{Host: host}
+		} else {
+			c.inFlight++
+		}
+	}
+	to, gen := c.state, c.gen
+	t.mu.Unlock()
+
+	t.notify(host, from, to)
+	return gen, err
+}
+
+// outcome is the outcome of a request.
+type outcome struct {
+	ignored bool
+	failed  bool
+	slow    bool
+}
+
+// release records the outcome of the request to the host allowed in the generation gen of
+// the circuit. The outcomes of the previous generations are discarded.
+func (t *BreakerTransport) release(host string, gen uint64, o outcome) {
+	t.mu.Lock()
+	c := t.circuits[host]
+	if c.gen != gen {
+		t.mu.Unlock()
+		return
+	}
+	now := t.now()
+	from := c.state
+	switch c.state {
+	case BreakerClosed:
+		if o.ignored {
+			break
+		}
+		c.buckets.add(now, o.failed, o.slow)
+		total, failed, slow := c.buckets.sum(now)
+		if total >= t.minRequests && (rate(failed, total) >= t.errorRate || (t.slowThreshold > 0 && rate(slow, total) >= t.slowRate)) {
+			c.transition(BreakerOpen, now)
+		}
+	case BreakerHalfOpen:
+		c.inFlight--
+		switch {
+		case o.ignored:
+		case o.failed || o.slow:
+			c.transition(BreakerOpen, now)
+		default:
+			c.successes++
+			if c.successes >= t.probes {
+				c.transition(BreakerClosed, now)
+			}
+		}
+	}
+	to := c.state
+	t.mu.Unlock()
+
+	t.notify(host, from, to)
+}
+
+// notify calls the state hook if the state has changed.
+func (t *BreakerTransport) notify(host string, from, to BreakerState) {
+	if from != to && t.hook != nil {
+		t.hook(host, from, to)
+	}
+}
+
+// rate returns n/total.
+func rate(n, total int) float64 {
+	return float64(n) / float64(total)
+}
+
+// circuit is the circuit of a single host.
+type circuit struct {
+	state     BreakerState
+	gen       uint64
+	openedAt  time.Time
+	inFlight  int // probe requests in flight
+	successes int // successful probe requests
+	buckets   *rollingWindow
+}
+
+// transition changes the state of the circuit and resets its counters.
+func (c *circuit) transition(state BreakerState, now time.Time) {
+	c.state = state
+	c.gen++
+	c.inFlight = 0
+	c.successes = 0
+	c.buckets.reset()
+	if state == BreakerOpen {
+		c.openedAt = now
+	}
+}
+
+// rollingWindow counts the outcomes of the requests in a rolling time window split into
+// the buckets, so the old outcomes expire a bucket at a time.
+type rollingWindow struct {
+	size    time.Duration // of a bucket
+	buckets []windowBucket
+}
+
+type windowBucket struct {
+	epoch  int64 // the number of the bucket since the Unix epoch
+	total  int
+	failed int
+	slow   int
+}
+
+// newRollingWindow returns a new rollingWindow of duration d split into n buckets.
+func newRollingWindow(d time.Duration, n int) *rollingWindow {
+	return &rollingWindow{
+		size:    max(d/time.Duration(n), time.Nanosecond),
+		buckets: make([]windowBucket, n),
+	}
+}
+
+// add records an outcome at time now.
+func (w *rollingWindow) add(now time.Time, failed, slow bool) {
+	epoch := now.UnixNano() / int64(w.size)
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if b.epoch != epoch {
+		*b = windowBucket{epoch: epoch}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+	if slow {
+		b.slow++
+	}
+}
+
+// sum returns the counts of the outcomes in the window ending at time now.
+func (w *rollingWindow) sum(now time.Time) (total, failed, slow int) {
+	epoch := now.UnixNano() / int64(w.size)
+	for _, b := range w.buckets {
+		if epoch-b.epoch < int64(len(w.buckets)) {
+			total += b.total
+			failed += b.failed
+			slow += b.slow
+		}
+	}
+	return total, failed, slow
+}
+
+// reset clears the window.
+func (w *rollingWindow) reset() {
+	clear(w.buckets)
+}
@@ -0,0 +1,183 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// breakerFixture is a BreakerTransport with a fake clock sending the requests to a
+// RoundTripper replying with the status code set in the fixture.
+type breakerFixture struct {
+	*BreakerTransport
+	now         time.Time
+	status      int // zero results in a connection reset error
+	latency     time.Duration
+	calls       int
+	transitions []string
+}
+
+func newBreakerFixture(opts ...BreakerOption) *breakerFixture {
+	f := &breakerFixture{now: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), status: http.StatusOK}
+	next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		f.calls++
+		f.now = f.now.Add(f.latency)
+		if f.status == 0 {
+			return nil, syscall.ECONNRESET
+		}
+		return &http.Response{StatusCode: f.status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	opts = append([]BreakerOption{
+		WithBreakerThreshold(0.5, 4),
+		WithBreakerStateHook(func(host string, from, to BreakerState) {
+			f.transitions = append(f.transitions, fmt.Sprintf("%s: %s -> %s", host, from, to))
+		}),
+	}, opts...)
+	f.BreakerTransport = NewBreakerTransport(next, opts...)
+	f.BreakerTransport.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *breakerFixture) send(host string, n int) (err error) {
+	for i := 0; i < n; i++ {
+		_, err = f.RoundTrip(httptest.NewRequest(http.MethodGet, "http://"+host+"/listings", nil))
+	}
+	return err
+}
+
+func TestBreakerTransport(t *testing.T) {
+	t.Run("should open circuit when error rate exceeds threshold", func(t *testing.T) {
+		f := newBreakerFixture()
+		f.send("listings", 2)
+		f.status = http.StatusServiceUnavailable
+		f.send("listings", 1)
+		f.status = 0
+		f.send("listings", 1)
+
+		err := f.send("listings", 1)
+
+		var coe *CircuitOpenError
+		if !errors.As(err, &coe) {
+			t.Fatalf("RoundTrip() error = %v, want *CircuitOpenError", err)
+		}
+		if coe.Host != "listings" || coe.RetryAfter != 30*time.Second {
+			t.Errorf("RoundTrip() error = %+v, want host listings and retry after 30s", coe)
+		}
+		if f.calls != 4 {
+			t.Errorf("RoundTrip() = calls got %d, want 4", f.calls)
+		}
+		if got := StatusCode(fmt.Errorf("fetching listing: %w", err)); got != http.StatusServiceUnavailable {
+			t.Errorf("StatusCode() = got %d, want %d", got, http.StatusServiceUnavailable)
+		}
+		if got := f.State("search"); got != BreakerClosed {
+			t.Errorf("State() = other host got %s, want %s", got, BreakerClosed)
+		}
+	})
+
+	t.Run("should not open circuit below min requests", func(t *testing.T) {
+		f := newBreakerFixture()
+		f.status = http.StatusBadGateway
+		f.send("listings", 3)
+
+		if got := f.State("listings"); got != BreakerClosed {
+			t.Errorf("State() = got %s, want %s", got, BreakerClosed)
+		}
+	})
+
+	t.Run("should forget errors out of window", func(t *testing.T) {
+		f := newBreakerFixture()
+		f.status = http.StatusBadGateway
+		f.send("listings", 3)
+		f.now = f.now.Add(11 * time.Second)
+		f.send("listings", 1)
+
+		if got := f.State("listings"); got != BreakerClosed {
+			t.Errorf("State() = got %s, want %s", got, BreakerClosed)
+		}
+	})
+
+	t.Run("should open circuit when slow rate exceeds threshold", func(t *testing.T) {
+		f := newBreakerFixture(WithBreakerSlowCalls(time.Second, 0.5))
+		f.latency = 2 * time.Second
+		f.send("listings", 4)
+
+		if got := f.State("listings"); got != BreakerOpen {
+			t.Errorf("State() = got %s, want %s", got, BreakerOpen)
+		}
+	})
+
+	t.Run("should close half-open circuit after successful probes", func(t *testing.T) {
+		f := newBreakerFixture(WithBreakerProbes(2))
+		f.status = 0
+		f.send("listings", 4)
+		f.now = f.now.Add(30 * time.Second)
+		f.status = http.StatusOK
+		f.send("listings", 2)
+
+		want := []string{
+			"listings: closed -> open",
+			"listings: open -> half-open",
+			"listings: half-open -> closed",
+		}
+		if !reflect.DeepEqual(f.transitions, want) {
+			t.Errorf("RoundTrip() = transitions got %v, want %v", f.transitions, want)
+		}
+	})
+
+	t.Run("should open half-open circuit after failed probe", func(t *testing.T) {
+		f := newBreakerFixture()
+		f.status = 0
+		f.send("listings", 4)
+		f.now = f.now.Add(30 * time.Second)
+		f.send("listings", 1)
+
+		want := []string{
+			"listings: closed -> open",
+			"listings: open -> half-open",
+			"listings: half-open -> open",
+		}
+		if !reflect.DeepEqual(f.transitions, want) {
+			t.Errorf("RoundTrip() = transitions got %v, want %v", f.transitions, want)
+		}
+	})
+
+	t.Run("should not record canceled requests", func(t *testing.T) {
+		f := newBreakerFixture()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		f.status = 0
+		for i := 0; i < 4; i++ {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://listings/listings", nil)
+			_, _ = f.RoundTrip(req)
+		}
+
+		if got := f.State("listings"); got != BreakerClosed {
+			t.Errorf("State() = got %s, want %s", got, BreakerClosed)
+		}
+	})
+}
+
+func TestBreakerTransport_Retry(t *testing.T) {
+	f := newBreakerFixture()
+	f.status = http.StatusServiceUnavailable
+	var retries int
+	rt := NewRetryTransport(f, WithBackoff(0, 0), WithMaxRetries(10), WithRetryHook(func(RetryAttempt) { retries++ }))
+
+	_, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://listings/listings", nil))
+
+	var coe *CircuitOpenError
+	if !errors.As(err, &coe) {
+		t.Errorf("RoundTrip() error = %v, want *CircuitOpenError", err)
+	}
+	if f.calls != 4 || retries != 4 {
+		t.Errorf("RoundTrip() = calls got %d and retries %d, want 4 and 4", f.calls, retries)
+	}
+}
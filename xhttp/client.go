@@ -0,0 +1,228 @@
+package xhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/httpbody"
+)
+
+// maxErrorBodySize is the maximum number of bytes of the error response bodies read by Client.
+const maxErrorBodySize = 1 << 20
+
+// ClientOption configures Client.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	httpClient *http.Client
+	baseURL    string
+	header     http.Header
+}
+
+// WithHTTPClient sets the http.Client used to send the requests, e.g. with a custom
+// transport. Defaults to an http.Client with a 30s timeout.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *clientConfig) {
+		c.httpClient = hc
+	}
+}
+
+// WithBaseURL sets the URL the relative request URLs are resolved against, e.g.
+// "https://listings.internal/api/v1".
+func WithBaseURL(u string) ClientOption {
+	return func(c *clientConfig) {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// WithClientHeader adds the header sent with every request, e.g. "User-Agent".
+func WithClientHeader(key, value string) ClientOption {
+	return func(c *clientConfig) {
+		c.header.Add(key, value)
+	}
+}
+
+// Client sends JSON requests to the other services, see DoJSON.
+//
+// Client is safe for concurrent use.
+type Client struct {
+	cfg clientConfig
+}
+
+// NewClient returns a new Client configured with the supplied options.
+func NewClient(opts ...ClientOption) *Client {
+	cfg := clientConfig{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		header:     make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Client{cfg: cfg}
+}
+
+// CallOption configures a single DoJSON call.
+type CallOption func(*callConfig)
+
+type callConfig struct {
+	header http.Header
+	query  url.Values
+}
+
+// WithRequestHeader sets the header of the request, replacing the default header with the same key.
+func WithRequestHeader(key, value string) CallOption {
+	return func(c *callConfig) {
+		c.header.Set(key, value)
+	}
+}
+
+// WithRequestQuery adds the query parameter to the request URL.
+func WithRequestQuery(key, value string) CallOption {
+	return func(c *callConfig) {
+		c.query.Add(key, value)
+	}
+}
+
+// ResponseError is returned by DoJSON for the responses with a non-2xx status code.
+//
+// ResponseError does not unwrap to the Problem, so that the status and the details of the
+// upstream service are not exposed by WriteError, i.e. it results in HTTP 500
+// StatusInternalServerError. Use errors.As to inspect it and map the status explicitly.
+type ResponseError struct {
+	// Method is the method of the request.
+	Method string
+	// URL is the URL of the request.
+	URL string
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Header is the header of the response.
+	Header http.Header
+	// Problem is the problem details document of the response, nil if the response is not
+	// a problem details document.
+	Problem *Problem
+	// Body is the body of the response, up to 1MiB.
+	Body []byte
+}
+
+// Error implements the error interface.
+func (e *ResponseError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Problem != nil && e.Problem.Detail != "" {
+		return msg + ": " + e.Problem.Detail
+	}
+	return msg
+}
+
+// DoJSON sends the request using the client and decodes the JSON response into Resp.
+//
+// A non-nil body is encoded as JSON. A relative url is resolved against the base URL of the
+// client, see WithBaseURL. Responses without a body, e.g. HTTP 204 StatusNoContent or empty
+// chunked responses, result in the zero Resp.
+//
+// Responses with a non-2xx status code are returned as *ResponseError carrying the decoded
+// problem details document, if any.
+func DoJSON[Resp any](ctx context.Context, c *Client, method, url string, body any, opts ...CallOption) (zero Resp, err error) {
+	req, err := c.newRequest(ctx, method, url, body, opts)
+	if err != nil {
+		return zero, err
+	}
+	res, err := c.cfg.httpClient.Do(req)
+	if err != nil {
+		return zero, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxErrorBodySize))
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return zero, newResponseError(req, res)
+	}
+	if res.StatusCode == http.StatusNoContent || res.ContentLength == 0 {
+		return zero, nil
+	}
+	// the length of the chunked responses is unknown, an empty body is read to tell
+	br := bufio.NewReader(res.Body)
+	if _, err = br.Peek(1); err == io.EOF {
+		return zero, nil
+	}
+	return httpbody.BindJSON[Resp](io.NopCloser(br))
+}
+
+// newRequest returns the request of a DoJSON call.
+func (c *Client) newRequest(ctx context.Context, method, rawURL string, body any, opts []CallOption) (*http.Request, error) {
+	cfg := &callConfig{
+		header: c.cfg.header.Clone(),
+		query:  make(url.Values),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing url: %w", err)
+	}
+	if !u.IsAbs() && c.cfg.baseURL != "" {
+		if u, err = url.Parse(c.cfg.baseURL + "/" + strings.TrimPrefix(rawURL, "/")); err != nil {
+			return nil, fmt.Errorf("parsing url: %w", err)
+		}
+	}
+	if len(cfg.query) > 0 {
+		q := u.Query()
+		for k, v := range cfg.query {
+			q[k] = append(q[k], v...)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		rc, err := httpbody.FromJSON(body)
+		if err != nil {
+			return nil, err
+		}
+		// buffered, so the request has Content-Length and can be rewound by the transport
+		data, _ := io.ReadAll(rc)
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header = cfg.header
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, "+ContentTypeProblemJSON)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// newResponseError returns the *ResponseError of the response.
+func newResponseError(req *http.Request, res *http.Response) *ResponseError {
+	e := &ResponseError{
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+	}
+	e.Body, _ = io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if typ, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); typ == ContentTypeProblemJSON {
+		var p Problem
+		if json.Unmarshal(e.Body, &p) == nil {
+			e.Problem = &p
+		}
+	}
+	return e
+}
@@ -0,0 +1,148 @@
+package xhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type clientListing struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title"`
+}
+
+func TestDoJSON(t *testing.T) {
+	var gotRequest *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequest = r
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		switch r.URL.Path {
+		case "/api/v1/listings":
+			Created(w, clientListing{ID: "123", Title: "flat"})
+		case "/api/v1/listings/123":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v1/listings/chunked":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		case "/api/v1/listings/404":
+			WriteError(w, r, ErrNotFound("listing not found"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("upstream failed"))
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(WithBaseURL(srv.URL+"/api/v1/"), WithClientHeader("User-Agent", "listings-client"))
+
+	t.Run("should send request and decode response", func(t *testing.T) {
+		got, err := DoJSON[clientListing](context.Background(), c, http.MethodPost, "/listings",
+			clientListing{Title: "flat"}, WithRequestQuery("dryRun", "false"), WithRequestHeader("X-Request-ID", "abc"))
+		if err != nil {
+			t.Fatalf("DoJSON() error = %v", err)
+		}
+		if want := (clientListing{ID: "123", Title: "flat"}); got != want {
+			t.Errorf("DoJSON() = got %v, want %v", got, want)
+		}
+		if want := `{"title":"flat"}`; gotBody != want {
+			t.Errorf("DoJSON() = request body got %s, want %s", gotBody, want)
+		}
+		wantHeader := map[string]string{
+			"Content-Type": "application/json",
+			"Accept":       "application/json, application/problem+json",
+			"User-Agent":   "listings-client",
+			"X-Request-ID": "abc",
+		}
+		for k, want := range wantHeader {
+			if got := gotRequest.Header.Get(k); got != want {
+				t.Errorf("DoJSON() = request header %s got %q, want %q", k, got, want)
+			}
+		}
+		if got := gotRequest.URL.RawQuery; got != "dryRun=false" {
+			t.Errorf("DoJSON() = request query got %q, want %q", got, "dryRun=false")
+		}
+	})
+
+	t.Run("should return zero response for no content", func(t *testing.T) {
+		got, err := DoJSON[*clientListing](context.Background(), c, http.MethodDelete, "listings/123", nil)
+		if err != nil {
+			t.Fatalf("DoJSON() error = %v", err)
+		}
+		if got != nil {
+			t.Errorf("DoJSON() = got %v, want nil", got)
+		}
+		if gotRequest.Header.Get("Content-Type") != "" {
+			t.Errorf("DoJSON() = request without body has Content-Type %q", gotRequest.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("should return zero response for empty chunked body", func(t *testing.T) {
+		got, err := DoJSON[*clientListing](context.Background(), c, http.MethodGet, "listings/chunked", nil)
+		if err != nil {
+			t.Fatalf("DoJSON() error = %v", err)
+		}
+		if got != nil {
+			t.Errorf("DoJSON() = got %v, want nil", got)
+		}
+	})
+
+	t.Run("should return problem of error response", func(t *testing.T) {
+		_, err := DoJSON[clientListing](context.Background(), c, http.MethodGet, "/listings/404", nil)
+
+		var re *ResponseError
+		if !errors.As(err, &re) {
+			t.Fatalf("DoJSON() error = %v, want *ResponseError", err)
+		}
+		want := &Problem{Title: "Not Found", Status: http.StatusNotFound, Detail: "listing not found", Instance: "/api/v1/listings/404"}
+		if !reflect.DeepEqual(re.Problem, want) {
+			t.Errorf("DoJSON() = problem got %v, want %v", re.Problem, want)
+		}
+		if re.StatusCode != http.StatusNotFound {
+			t.Errorf("DoJSON() = status got %d, want %d", re.StatusCode, http.StatusNotFound)
+		}
+		wantErr := "GET " + srv.URL + "/api/v1/listings/404: 404 Not Found: listing not found"
+		if err.Error() != wantErr {
+			t.Errorf("DoJSON() error = %v, want %v", err, wantErr)
+		}
+		if got := StatusCode(err); got != http.StatusInternalServerError {
+			t.Errorf("StatusCode() = got %d, want %d", got, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("should return body of error response", func(t *testing.T) {
+		_, err := DoJSON[clientListing](context.Background(), c, http.MethodGet, srv.URL+"/other", nil)
+
+		var re *ResponseError
+		if !errors.As(err, &re) {
+			t.Fatalf("DoJSON() error = %v, want *ResponseError", err)
+		}
+		if re.Problem != nil {
+			t.Errorf("DoJSON() = problem got %v, want nil", re.Problem)
+		}
+		if got := string(re.Body); got != "upstream failed" {
+			t.Errorf("DoJSON() = body got %s, want %s", got, "upstream failed")
+		}
+	})
+
+	t.Run("should return error of invalid response", func(t *testing.T) {
+		_, err := DoJSON[int](context.Background(), c, http.MethodPost, "/listings", nil)
+
+		var se *json.UnmarshalTypeError
+		if !errors.As(err, &se) {
+			t.Errorf("DoJSON() error = %v, want *json.UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("should return error of unencodable body", func(t *testing.T) {
+		_, err := DoJSON[clientListing](context.Background(), c, http.MethodPost, "/listings", func() {})
+		if err == nil {
+			t.Errorf("DoJSON() = expected error for unencodable body")
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package xhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ResponseOption configures a single response written by WriteResponse and the helpers
+// built on top of it.
+type ResponseOption func(*responseConfig)
+
+type responseConfig struct {
+	request      *http.Request
+	etag         string
+	lastModified time.Time
+}
+
+// Conditional enables the conditional request handling of the request r.
+//
+// The response gets the ETag header, computed as a strong entity tag from the serialized
+// body unless supplied using WithETag or WithVersion, and the Last-Modified header if
+// supplied using WithLastModified. The responses without a body get only the supplied
+// ETag. For GET and HEAD requests, the conditional headers are evaluated: the request is
+// replied with an HTTP 304 StatusNotModified without a body if the client representation
+// is up-to-date, see If-None-Match and If-Modified-Since, or with an HTTP 412
+// StatusPreconditionFailed problem details document if If-Match or If-Unmodified-Since fail.
+//
+// Only 2xx responses are subject to the conditional request handling. Preconditions of
+// the state changing requests (e.g. If-Match of PUT and PATCH) have to be evaluated before
+// the change is made, see CheckPreconditions.
+func Conditional(r *http.Request) ResponseOption {
+	return func(c *responseConfig) {
+		c.request = r
+	}
+}
+
+// WithETag sets the entity tag of the response instead of computing it from the serialized
+// body. The value is quoted if it is not already, weak tags (W/"...") are kept as is.
+func WithETag(etag string) ResponseOption {
+	return func(c *responseConfig) {
+		c.etag = quoteETag(etag)
+	}
+}
+
+// WithVersion sets the entity tag of the response to the strong entity tag of the supplied
+// resource version instead of computing it from the serialized body.
+func WithVersion(version any) ResponseOption {
+	return func(c *responseConfig) {
+		c.etag = quoteETag(fmt.Sprint(version))
+	}
+}
+
+// WithLastModified sets the Last-Modified header of the response, it is used to evaluate
+// the If-Modified-Since header of the conditional requests.
+func WithLastModified(t time.Time) ResponseOption {
+	return func(c *responseConfig) {
+		c.lastModified = t
+	}
+}
+
+// CheckPreconditions evaluates the If-Match, If-Unmodified-Since and If-None-Match headers
+// of the state changing request r (e.g. PUT, PATCH or DELETE) against the supplied entity
+// tag and last modification time of the current resource representation. Zero values
+// are treated as unknown.
+//
+// If any of the preconditions fails, the request is replied with an HTTP 412
+// StatusPreconditionFailed problem details document and false is returned, in which case
+// the caller should not perform the change.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	etag = quoteETag(etag)
+	if evaluatePreconditions(r, etag, lastModified) == http.StatusPreconditionFailed {
+		WriteProblem(w, NewProblem(http.StatusPreconditionFailed, "resource has been modified"))
+		return false
+	}
+	return true
+}
+
+// writeConditional writes the conditional response headers and reports whether the request
+// has been replied with an HTTP 304 StatusNotModified or HTTP 412 StatusPreconditionFailed.
+// The entity tag is computed from the body unless it is nil.
+func (c *responseConfig) writeConditional(w http.ResponseWriter, code int, body []byte) bool {
+	if c.request == nil || code < 200 || code > 299 {
+		return false
+	}
+	etag := c.etag
+	if etag == "" && body != nil {
+		etag = strongETag(body)
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !c.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", c.lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.request.Method != http.MethodGet && c.request.Method != http.MethodHead {
+		return false
+	}
+	switch evaluatePreconditions(c.request, etag, c.lastModified) {
+	case http.StatusNotModified:
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	case http.StatusPreconditionFailed:
+		WriteProblem(w, NewProblem(http.StatusPreconditionFailed, "resource has been modified"))
+		return true
+	}
+	return false
+}
+
+// evaluatePreconditions evaluates the conditional headers of r in the order defined by
+// RFC 9110 section 13.2.2 and returns HTTP 412 StatusPreconditionFailed, HTTP 304
+// StatusNotModified or 0 if the request should be processed.
+func evaluatePreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(ius) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !lastModified.IsZero() {
+		if !lastModified.Truncate(time.Second).After(ims) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether etag matches any of the entity tags of the header value using
+// either the weak or the strong comparison.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return etag != ""
+	}
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// strongETag returns a strong entity tag computed from the SHA-256 digest of the body.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// quoteETag quotes the entity tag if it is not already quoted.
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
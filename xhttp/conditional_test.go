@@ -0,0 +1,263 @@
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditional(t *testing.T) {
+	body := &dummyResponse{Value: "test"}
+	etag := strongETag([]byte(`{"value":"test"}`))
+	modified := time.Date(2023, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		opts       []ResponseOption
+		wantStatus int
+		wantETag   string
+		wantBody   string
+	}{
+		{
+			name:       "should set computed etag",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+		{
+			name:       "should reply not modified if etag matches",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"other", W/` + etag},
+			wantStatus: http.StatusNotModified,
+			wantETag:   etag,
+		},
+		{
+			name:       "should reply with body if etag does not match",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"other"`},
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+		{
+			name:       "should use supplied version",
+			method:     http.MethodHead,
+			headers:    map[string]string{"If-None-Match": `"v3"`},
+			opts:       []ResponseOption{WithVersion("v3")},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"v3"`,
+		},
+		{
+			name:       "should use supplied weak etag",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"abc"`},
+			opts:       []ResponseOption{WithETag(`W/"abc"`)},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `W/"abc"`,
+		},
+		{
+			name:       "should reply not modified if not modified since",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			opts:       []ResponseOption{WithLastModified(modified.Add(500 * time.Millisecond))},
+			wantStatus: http.StatusNotModified,
+			wantETag:   etag,
+		},
+		{
+			name:       "should reply with body if modified since",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			opts:       []ResponseOption{WithLastModified(modified)},
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+		{
+			name:   "should ignore if modified since if if none match is present",
+			method: http.MethodGet,
+			headers: map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			opts:       []ResponseOption{WithLastModified(modified)},
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+		{
+			name:       "should reply precondition failed if etag does not match",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Match": `"other"`},
+			wantStatus: http.StatusPreconditionFailed,
+			wantETag:   etag,
+			wantBody:   `{"title":"Precondition Failed","status":412,"detail":"resource has been modified"}`,
+		},
+		{
+			name:       "should reply precondition failed if modified since",
+			method:     http.MethodHead,
+			headers:    map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			opts:       []ResponseOption{WithLastModified(modified)},
+			wantStatus: http.StatusPreconditionFailed,
+			wantETag:   etag,
+			wantBody:   `{"title":"Precondition Failed","status":412,"detail":"resource has been modified"}`,
+		},
+		{
+			name:       "should reply with body if etag matches",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Match": etag},
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+		{
+			name:       "should not evaluate conditions of unsafe methods",
+			method:     http.MethodPut,
+			headers:    map[string]string{"If-None-Match": etag},
+			wantStatus: http.StatusOK,
+			wantETag:   etag,
+			wantBody:   `{"value":"test"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/resource", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			OK(w, body, append(tt.opts, Conditional(r))...)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Conditional() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("Conditional() = etag got %s, want %s", got, tt.wantETag)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Conditional() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestConditional_NilBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		opts       []ResponseOption
+		wantStatus int
+		wantETag   string
+	}{
+		{
+			name:       "should set supplied etag",
+			opts:       []ResponseOption{WithVersion(7)},
+			wantStatus: http.StatusOK,
+			wantETag:   `"7"`,
+		},
+		{
+			name:       "should not compute etag",
+			headers:    map[string]string{"If-None-Match": "*"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should reply not modified if supplied etag matches",
+			headers:    map[string]string{"If-None-Match": `"7"`},
+			opts:       []ResponseOption{WithVersion(7)},
+			wantStatus: http.StatusNotModified,
+			wantETag:   `"7"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			OK(w, nil, append(tt.opts, Conditional(r))...)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Conditional() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("Conditional() = etag got %s, want %s", got, tt.wantETag)
+			}
+			if got := w.Body.Len(); got != 0 {
+				t.Errorf("Conditional() = body length got %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestConditional_ErrorResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	r.Header.Set("If-None-Match", "*")
+	w := httptest.NewRecorder()
+	BadRequest(w, &errorResponse{Message: "invalid"}, Conditional(r))
+
+	if got := w.Code; got != http.StatusBadRequest {
+		t.Errorf("Conditional() = status got %d, want %d", got, http.StatusBadRequest)
+	}
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("Conditional() = expected etag to be empty got %s", got)
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2023, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		etag         string
+		lastModified time.Time
+		want         bool
+	}{
+		{name: "should pass without conditional headers", etag: "v1", want: true},
+		{name: "should pass if etag matches", headers: map[string]string{"If-Match": `"v0", "v1"`}, etag: "v1", want: true},
+		{name: "should fail if etag does not match", headers: map[string]string{"If-Match": `"v0"`}, etag: "v1", want: false},
+		{name: "should fail weak etag comparison", headers: map[string]string{"If-Match": `W/"v1"`}, etag: `W/"v1"`, want: false},
+		{name: "should pass any etag of existing resource", headers: map[string]string{"If-Match": "*"}, etag: "v1", want: true},
+		{name: "should fail any etag of missing resource", headers: map[string]string{"If-Match": "*"}, want: false},
+		{name: "should fail if resource exists", headers: map[string]string{"If-None-Match": "*"}, etag: "v1", want: false},
+		{name: "should pass if resource is missing", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{
+			name:         "should fail if modified since",
+			headers:      map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			lastModified: modified,
+			want:         false,
+		},
+		{
+			name:         "should pass if not modified since",
+			headers:      map[string]string{"If-Unmodified-Since": modified.Format(http.TimeFormat)},
+			lastModified: modified,
+			want:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/resource", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			if got := CheckPreconditions(w, r, tt.etag, tt.lastModified); got != tt.want {
+				t.Fatalf("CheckPreconditions() = got %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				return
+			}
+			if got := w.Code; got != http.StatusPreconditionFailed {
+				t.Errorf("CheckPreconditions() = status got %d, want %d", got, http.StatusPreconditionFailed)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentTypeProblemJSON {
+				t.Errorf("CheckPreconditions() = content type got %q, want %q", got, ContentTypeProblemJSON)
+			}
+		})
+	}
+}
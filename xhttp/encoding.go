@@ -0,0 +1,182 @@
+package xhttp
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"sync"
+)
+
+// Encoder serializes response bodies into a specific media type.
+type Encoder interface {
+	// ContentType returns the value of the Content-Type header of the encoded responses.
+	ContentType() string
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+}
+
+// NewEncoder returns a new Encoder of the supplied content type that serializes values
+// using the supplied marshal function.
+func NewEncoder(contentType string, marshal func(v any) ([]byte, error)) Encoder {
+	return &encoderFunc{contentType: contentType, marshal: marshal}
+}
+
+type encoderFunc struct {
+	contentType string
+	marshal     func(v any) ([]byte, error)
+}
+
+func (e *encoderFunc) ContentType() string           { return e.contentType }
+func (e *encoderFunc) Marshal(v any) ([]byte, error) { return e.marshal(v) }
+
+// JSONEncoder serializes values as application/json. The zero value behaves as json.Marshal.
+type JSONEncoder struct {
+	// Prefix and Indent are used to indent the output as json.MarshalIndent does.
+	Prefix, Indent string
+	// DisableHTMLEscape disables escaping of the <, > and & characters in JSON strings.
+	DisableHTMLEscape bool
+}
+
+// ContentType implements Encoder interface.
+func (JSONEncoder) ContentType() string { return "application/json" }
+
+// Marshal implements Encoder interface.
+func (e JSONEncoder) Marshal(v any) ([]byte, error) {
+	if e == (JSONEncoder{}) {
+		return json.Marshal(v)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetIndent(e.Prefix, e.Indent)
+	enc.SetEscapeHTML(!e.DisableHTMLEscape)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// json.Encoder terminates each value with a newline unlike json.Marshal
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// XMLEncoder serializes values as application/xml using xml.Marshal.
+type XMLEncoder struct{}
+
+// ContentType implements Encoder interface.
+func (XMLEncoder) ContentType() string { return "application/xml" }
+
+// Marshal implements Encoder interface.
+func (XMLEncoder) Marshal(v any) ([]byte, error) { return xml.Marshal(v) }
+
+// TextEncoder serializes values as text/plain.
+//
+// Strings, byte slices, encoding.TextMarshaler and fmt.Stringer values are written as is,
+// any other value is formatted using fmt.Sprint.
+type TextEncoder struct{}
+
+// ContentType implements Encoder interface.
+func (TextEncoder) ContentType() string { return "text/plain; charset=utf-8" }
+
+// Marshal implements Encoder interface.
+func (TextEncoder) Marshal(v any) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case encoding.TextMarshaler:
+		return t.MarshalText()
+	case fmt.Stringer:
+		return []byte(t.String()), nil
+	}
+	return []byte(fmt.Sprint(v)), nil
+}
+
+// DefaultEncoders is the default Encoders registry used by NegotiateContent. It contains
+// JSON, XML and plain text encoders, in that order of preference.
+var DefaultEncoders = NewEncoders(JSONEncoder{}, XMLEncoder{}, TextEncoder{})
+
+// Encoders is a registry of the response encoders used for the content negotiation.
+//
+// The order of registration defines the server preference: if multiple encoders are
+// equally acceptable by the client, the earliest registered one is selected.
+//
+// Encoders is safe for concurrent use.
+type Encoders struct {
+	mu       sync.RWMutex
+	encoders []Encoder
+}
+
+// NewEncoders returns a new Encoders registry with the supplied encoders registered.
+func NewEncoders(encoders ...Encoder) *Encoders {
+	e := &Encoders{}
+	for _, enc := range encoders {
+		e.Register(enc)
+	}
+	return e
+}
+
+// Register adds the supplied encoder to the registry. If an encoder of the same media type
+// is already registered it gets replaced keeping its position.
+func (e *Encoders) Register(enc Encoder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	typ := mediaType(enc.ContentType())
+	for i, existing := range e.encoders {
+		if mediaType(existing.ContentType()) == typ {
+			e.encoders[i] = enc
+			return
+		}
+	}
+	e.encoders = append(e.encoders, enc)
+}
+
+// Negotiate returns the encoder that is the most acceptable according to the supplied
+// Accept header value, or false if none of the registered encoders is acceptable.
+//
+// An empty or malformed accept value means that any media type is acceptable.
+func (e *Encoders) Negotiate(accept string) (Encoder, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.encoders) == 0 {
+		return nil, false
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return e.encoders[0], true
+	}
+
+	var (
+		best  Encoder
+		bestQ float64
+	)
+	for _, enc := range e.encoders {
+		if q := quality(ranges, mediaType(enc.ContentType())); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best, best != nil
+}
+
+// ContentTypes returns the content types of all the registered encoders.
+func (e *Encoders) ContentTypes() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := make([]string, 0, len(e.encoders))
+	for _, enc := range e.encoders {
+		res = append(res, enc.ContentType())
+	}
+	return res
+}
+
+// mediaType returns the media type of the content type without parameters.
+func mediaType(contentType string) string {
+	typ, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return typ
+}
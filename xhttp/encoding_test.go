@@ -0,0 +1,94 @@
+package xhttp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestTextEncoder_Marshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+		want  string
+	}{
+		{name: "should write string as is", input: "value", want: "value"},
+		{name: "should write bytes as is", input: []byte("bytes"), want: "bytes"},
+		{name: "should use text marshaler", input: net.IPv4(127, 0, 0, 1), want: "127.0.0.1"},
+		{name: "should format other values", input: 42, want: "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TextEncoder{}.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoders_Register(t *testing.T) {
+	custom := NewEncoder("application/json; charset=utf-8", func(v any) ([]byte, error) {
+		return nil, errors.New("not implemented")
+	})
+	encoders := NewEncoders(JSONEncoder{}, TextEncoder{})
+	encoders.Register(custom)
+	encoders.Register(NewEncoder("text/csv", nil))
+
+	want := []string{"application/json; charset=utf-8", "text/plain; charset=utf-8", "text/csv"}
+	if got := encoders.ContentTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Register() = content types got %v, want %v", got, want)
+	}
+}
+
+func TestEncoders_Negotiate(t *testing.T) {
+	encoders := NewEncoders(JSONEncoder{}, XMLEncoder{}, TextEncoder{})
+
+	tests := []struct {
+		name   string
+		accept string
+		want   Encoder
+		wantOk bool
+	}{
+		{name: "should select first encoder if accept is empty", accept: "", want: JSONEncoder{}, wantOk: true},
+		{name: "should select first encoder if accept is malformed", accept: "json", want: JSONEncoder{}, wantOk: true},
+		{name: "should select first encoder for any type", accept: "*/*", want: JSONEncoder{}, wantOk: true},
+		{name: "should select exact match", accept: "application/xml", want: XMLEncoder{}, wantOk: true},
+		{name: "should match type wildcard", accept: "text/*", want: TextEncoder{}, wantOk: true},
+		{
+			name:   "should select the highest quality",
+			accept: "application/json;q=0.5, text/plain;q=0.8, */*;q=0.1",
+			want:   TextEncoder{},
+			wantOk: true,
+		},
+		{
+			name:   "should prefer the most specific range",
+			accept: "application/*;q=0.9, application/json;q=0.2",
+			want:   XMLEncoder{},
+			wantOk: true,
+		},
+		{
+			name:   "should break ties by registration order",
+			accept: "text/plain, application/xml",
+			want:   XMLEncoder{},
+			wantOk: true,
+		},
+		{name: "should exclude ranges with zero quality", accept: "application/json;q=0, */*;q=0", wantOk: false},
+		{name: "should not match unsupported types", accept: "image/png", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := encoders.Negotiate(tt.accept)
+			if ok != tt.wantOk {
+				t.Fatalf("Negotiate() = ok got %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Negotiate() = got %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package xhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
+)
+
+// StatusError is an error that carries the HTTP status code of the response, a public
+// message that is safe to expose to the client and an optional internal cause.
+type StatusError struct {
+	// Code is the HTTP status code of the response.
+	Code int
+	// Message is the public message of the error. If empty, the status text is used.
+	Message string
+	// Err is the internal cause of the error.
+	Err error
+}
+
+// NewStatusError returns a new StatusError with the supplied status code, public message and cause.
+func NewStatusError(code int, message string, cause error) *StatusError {
+	return &StatusError{Code: code, Message: message, Err: cause}
+}
+
+// ErrBadRequest returns a new StatusError with an HTTP 400 StatusBadRequest code.
+func ErrBadRequest(message string) *StatusError {
+	return NewStatusError(http.StatusBadRequest, message, nil)
+}
+
+// ErrUnauthorized returns a new StatusError with an HTTP 401 StatusUnauthorized code.
+func ErrUnauthorized(message string) *StatusError {
+	return NewStatusError(http.StatusUnauthorized, message, nil)
+}
+
+// ErrForbidden returns a new StatusError with an HTTP 403 StatusForbidden code.
+func ErrForbidden(message string) *StatusError {
+	return NewStatusError(http.StatusForbidden, message, nil)
+}
+
+// ErrNotFound returns a new StatusError with an HTTP 404 StatusNotFound code.
+func ErrNotFound(message string) *StatusError {
+	return NewStatusError(http.StatusNotFound, message, nil)
+}
+
+// ErrConflict returns a new StatusError with an HTTP 409 StatusConflict code.
+func ErrConflict(message string) *StatusError {
+	return NewStatusError(http.StatusConflict, message, nil)
+}
+
+// ErrUnprocessableEntity returns a new StatusError with an HTTP 422 StatusUnprocessableEntity code.
+func ErrUnprocessableEntity(message string) *StatusError {
+	return NewStatusError(http.StatusUnprocessableEntity, message, nil)
+}
+
+// ErrTooManyRequests returns a new StatusError with an HTTP 429 StatusTooManyRequests code.
+func ErrTooManyRequests(message string) *StatusError {
+	return NewStatusError(http.StatusTooManyRequests, message, nil)
+}
+
+// ErrInternal returns a new StatusError with an HTTP 500 StatusInternalServerError code
+// wrapping the supplied cause.
+func ErrInternal(cause error) *StatusError {
+	return NewStatusError(http.StatusInternalServerError, "", cause)
+}
+
+// ErrServiceUnavailable returns a new StatusError with an HTTP 503 StatusServiceUnavailable code.
+func ErrServiceUnavailable(message string) *StatusError {
+	return NewStatusError(http.StatusServiceUnavailable, message, nil)
+}
+
+// Wrap sets the internal cause of the error and returns the StatusError to allow chaining.
+func (e *StatusError) Wrap(cause error) *StatusError {
+	e.Err = cause
+	return e
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Code)
+	}
+	if e.Err == nil {
+		return msg
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the internal cause of the error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code for the supplied error.
+//
+// The code is taken from the first *StatusError or *Problem found in the err tree using
+// errors.As, a *http.MaxBytesError results in HTTP 413 StatusRequestEntityTooLarge, a
+// *CircuitOpenError results in HTTP 503 StatusServiceUnavailable and a *FieldError or
+// *xvalidate.FieldError results in HTTP 422 StatusUnprocessableEntity. For errors joined
+// using errors.Join (e.g. xslices.MapWithError with fail-fast disabled) or xvalidate.Errors,
+// the highest code of the joined errors is returned. Any other error results in HTTP 500
+// StatusInternalServerError.
+func StatusCode(err error) int {
+	if errs := joinedErrors(err); errs != nil {
+		code := 0
+		for _, err := range errs {
+			if c := StatusCode(err); c > code {
+				code = c
+			}
+		}
+		return code
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	var p *Problem
+	if errors.As(err, &p) && p.Status != 0 {
+		return p.Status
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var coe *CircuitOpenError
+	if errors.As(err, &coe) {
+		return http.StatusServiceUnavailable
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return http.StatusUnprocessableEntity
+	}
+	var ve *xvalidate.FieldError
+	if errors.As(err, &ve) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteError replies to the request with a problem details document describing err.
+// The status code of the response is resolved using StatusCode.
+//
+// The details of the 5xx errors are not exposed to the client: only the public message
+// of a *StatusError is written, any other error is replaced with the status text.
+// For the joined errors, the public messages of all the errors are written.
+//
+// FieldError values found in the err tree are written as the "errors" extension member.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err, StatusCode(err))
+}
+
+// writeError replies to the request with a problem details document describing err
+// using the supplied status code.
+func writeError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	var p *Problem
+	if errors.As(err, &p) && p.Status == status && joinedErrors(err) == nil {
+		problem := *p
+		if problem.Instance == "" && r != nil {
+			problem.Instance = r.URL.Path
+		}
+		WriteProblem(w, &problem)
+		return
+	}
+	problem := NewProblem(status, errorDetail(err, status)).WithErrors(FieldErrors(err)...)
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+	WriteProblem(w, problem)
+}
+
+// errorDetail returns the public detail of err for the response with the supplied status code.
+func errorDetail(err error, status int) string {
+	if errs := joinedErrors(err); errs != nil {
+		if status >= http.StatusInternalServerError {
+			return http.StatusText(status)
+		}
+		details := make([]string, 0, len(errs))
+		for _, err := range errs {
+			details = append(details, errorDetail(err, status))
+		}
+		return strings.Join(details, "; ")
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch {
+		case se.Message != "":
+			return se.Message
+		case status >= http.StatusInternalServerError:
+			return http.StatusText(status)
+		case se.Err != nil:
+			return se.Err.Error()
+		}
+		return http.StatusText(se.Code)
+	}
+	var p *Problem
+	if errors.As(err, &p) {
+		return p.Detail
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return fmt.Sprintf("request body exceeds the limit of %d bytes", mbe.Limit)
+	}
+	if status >= http.StatusInternalServerError {
+		return http.StatusText(status)
+	}
+	return err.Error()
+}
+
+// joinedErrors follows the chain of the wrapped errors of err and returns the errors joined
+// using errors.Join function, or nil if the chain ends or reaches a *StatusError, *Problem or
+// *FieldError first.
+func joinedErrors(err error) []error {
+	for err != nil {
+		switch e := err.(type) {
+		case *StatusError, *Problem, *FieldError:
+			return nil
+		case interface{ Unwrap() []error }:
+			return e.Unwrap()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package xhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xslices"
+)
+
+func TestStatusError_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *StatusError
+		want string
+	}{
+		{name: "should use status text if message is empty", err: ErrForbidden(""), want: "Forbidden"},
+		{name: "should use message", err: ErrConflict("listing already exists"), want: "listing already exists"},
+		{
+			name: "should include cause",
+			err:  ErrInternal(errors.New("connection refused")),
+			want: "Internal Server Error: connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "should return status error code", err: ErrNotFound("not found"), want: http.StatusNotFound},
+		{
+			name: "should unwrap status error",
+			err:  fmt.Errorf("getting listing: %w", ErrNotFound("").Wrap(cause)),
+			want: http.StatusNotFound,
+		},
+		{name: "should return problem status", err: NewProblem(http.StatusConflict, ""), want: http.StatusConflict},
+		{name: "should return unprocessable for field errors", err: &FieldError{}, want: http.StatusUnprocessableEntity},
+		{name: "should return internal server error for unknown errors", err: cause, want: http.StatusInternalServerError},
+		{
+			name: "should return highest code of joined errors",
+			err:  fmt.Errorf("mapping: %w", errors.Join(ErrBadRequest(""), ErrConflict(""))),
+			want: http.StatusConflict,
+		},
+		{
+			name: "should return internal server error if any of joined errors is unknown",
+			err:  errors.Join(ErrBadRequest(""), cause),
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusCode(tt.err); got != tt.want {
+				t.Errorf("StatusCode() = got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	_, mappingErr := xslices.MapWithError[[]string, []int]([]string{"1", "a", "b"}, func(s string) (int, error) {
+		if s != "1" {
+			return 0, ErrUnprocessableEntity(fmt.Sprintf("%s is not a number", s))
+		}
+		return 1, nil
+	}, false)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should write public message of status error",
+			err:        fmt.Errorf("getting listing: %w", ErrNotFound("listing not found").Wrap(errors.New("sql: no rows"))),
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"title":"Not Found","status":404,"detail":"listing not found","instance":"/listings/1"}`,
+		},
+		{
+			name:       "should write cause of client errors without message",
+			err:        ErrBadRequest("").Wrap(errors.New("id is malformed")),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"title":"Bad Request","status":400,"detail":"id is malformed","instance":"/listings/1"}`,
+		},
+		{
+			name:       "should hide cause of server errors",
+			err:        ErrInternal(errors.New("connection refused")),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"title":"Internal Server Error","status":500,"detail":"Internal Server Error","instance":"/listings/1"}`,
+		},
+		{
+			name:       "should hide unknown errors",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"title":"Internal Server Error","status":500,"detail":"Internal Server Error","instance":"/listings/1"}`,
+		},
+		{
+			name:       "should write problem as is",
+			err:        fmt.Errorf("wrapped: %w", &Problem{Type: "https://example.com/out-of-credit", Status: http.StatusForbidden}),
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"type":"https://example.com/out-of-credit","status":403,"instance":"/listings/1"}`,
+		},
+		{
+			name:       "should write all messages of joined errors",
+			err:        mappingErr,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"title":"Unprocessable Entity","status":422,"detail":"a is not a number; b is not a number",` +
+				`"instance":"/listings/1"}`,
+		},
+		{
+			name:       "should write field errors",
+			err:        errors.Join(&FieldError{Field: "price", Message: "is required"}),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"detail":"price: is required","errors":[{"field":"price","message":"is required"}],` +
+				`"instance":"/listings/1","status":422,"title":"Unprocessable Entity"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteError(w, httptest.NewRequest(http.MethodGet, "/listings/1", nil), tt.err)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("WriteError() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("WriteError() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
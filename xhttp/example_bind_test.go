@@ -0,0 +1,44 @@
+package xhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+func ExampleBindQuery() {
+	type searchQuery struct {
+		PriceMin *int          `query:"price_min"`
+		PriceMax *int          `query:"price_max"`
+		Rooms    []int         `query:"rooms"` // ?rooms=1,2 or ?rooms=1&rooms=2
+		Since    time.Time     `query:"since"`
+		MaxAge   time.Duration `query:"max_age"`
+	}
+
+	http.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		query, err := BindQuery[searchQuery](r)
+		if err != nil {
+			// replies with an HTTP 422 listing the invalid parameters
+			WriteError(w, r, err)
+			return
+		}
+		OK(w, query)
+	})
+}
+
+func ExampleBindValidQuery() {
+	type searchQuery struct {
+		Query string `query:"q" validate:"required,min=3"`
+		Sort  string `query:"sort" validate:"omitempty,oneof=price date"`
+		Limit int    `query:"limit" validate:"omitempty,max=100"`
+	}
+
+	http.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		query, err := BindValidQuery[searchQuery](r)
+		if err != nil {
+			// replies with an HTTP 422 listing the invalid parameters, e.g. {"field":"q","message":"is required"}
+			WriteError(w, r, err)
+			return
+		}
+		OK(w, query)
+	})
+}
@@ -0,0 +1,74 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func ExampleDoJSON() {
+	type listing struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	client := NewClient(
+		WithBaseURL("https://listings.internal/api/v1"),
+		WithClientHeader("User-Agent", "search-service"),
+	)
+
+	l, err := DoJSON[listing](context.Background(), client, http.MethodGet, "/listings/123", nil,
+		WithRequestQuery("fields", "title"))
+	var re *ResponseError
+	switch {
+	case errors.As(err, &re) && re.StatusCode == http.StatusNotFound:
+		fmt.Println("listing not found")
+	case err != nil:
+		fmt.Println("fetching listing:", err)
+	default:
+		fmt.Println(l.Title)
+	}
+}
+
+func ExampleNewRetryTransport() {
+	// a budget shared by all the clients of the listings service
+	budget := NewRetryBudget(0.1, 5)
+	transport := NewRetryTransport(http.DefaultTransport,
+		WithMaxRetries(2),
+		WithBackoff(50*time.Millisecond, time.Second),
+		WithRetryBudget(budget),
+		WithRetryHook(func(a RetryAttempt) {
+			slog.Info("retrying request", "url", a.Request.URL.Redacted(), "attempt", a.Attempt, "status", a.StatusCode, "error", a.Err)
+		}),
+	)
+
+	client := NewClient(
+		WithBaseURL("https://listings.internal/api/v1"),
+		WithHTTPClient(&http.Client{Transport: transport, Timeout: 10 * time.Second}),
+	)
+	_ = client
+}
+
+func ExampleNewBreakerTransport() {
+	breaker := NewBreakerTransport(http.DefaultTransport,
+		WithBreakerThreshold(0.5, 20),
+		WithBreakerSlowCalls(2*time.Second, 0.8),
+		WithBreakerOpenTimeout(15*time.Second),
+		WithBreakerStateHook(func(host string, from, to BreakerState) {
+			slog.Warn("circuit breaker state changed", "host", host, "from", from, "to", to)
+		}),
+	)
+	client := NewClient(WithHTTPClient(&http.Client{Transport: NewRetryTransport(breaker)}))
+
+	http.Handle("/listings/123", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l, err := DoJSON[map[string]any](r.Context(), client, http.MethodGet, "https://listings.internal/api/v1/listings/123", nil)
+		if err != nil {
+			// HTTP 503 StatusServiceUnavailable if the circuit is open
+			WriteError(w, r, err)
+			return
+		}
+		OK(w, l)
+	}))
+}
@@ -0,0 +1,32 @@
+package xhttp
+
+import (
+	"net/http"
+	"time"
+)
+
+func ExampleConditional() {
+	http.HandleFunc("/dictionaries/cities", func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 304 NotModified if the client already has the current representation
+		OK(w, &dummyResponse{Value: "cities"}, Conditional(r))
+	})
+}
+
+func ExampleCheckPreconditions() {
+	type listing struct {
+		Version   int       `json:"version"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+	current := &listing{Version: 3, UpdatedAt: time.Now()}
+
+	http.HandleFunc("/listings/1", func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 412 PreconditionFailed if the client has modified stale representation
+		if !CheckPreconditions(w, r, "3", current.UpdatedAt) {
+			return
+		}
+		// update logic here
+		current.Version++
+
+		OK(w, current, Conditional(r), WithVersion(current.Version))
+	})
+}
@@ -0,0 +1,30 @@
+package xhttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+func ExampleWriteError() {
+	errNoRows := errors.New("sql: no rows in result set")
+
+	http.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		// service logic returning an error
+		err := fmt.Errorf("getting listing: %w", ErrNotFound("listing 123 not found").Wrap(errNoRows))
+
+		// respond with HTTP 404 NotFound problem details document
+		WriteError(w, r, err)
+	})
+}
+
+func ExampleStatusCode() {
+	err := fmt.Errorf("getting listing: %w", ErrConflict("listing 123 is archived"))
+
+	fmt.Println(StatusCode(err))
+	fmt.Println(StatusCode(errors.New("connection refused")))
+
+	// Output:
+	// 409
+	// 500
+}
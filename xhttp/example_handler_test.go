@@ -0,0 +1,28 @@
+package xhttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+func ExampleHandle() {
+	type request struct {
+		ID    string `path:"id"`
+		Title string `json:"title"`
+		Force bool   `query:"force"`
+	}
+	type response struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+
+	update := func(ctx context.Context, req *request) (*response, error) {
+		// service logic here
+		return &response{ID: req.ID, Title: req.Title}, nil
+	}
+
+	http.Handle("/listings/", Handle(update, WithPathParams(func(r *http.Request, name string) string {
+		return strings.TrimPrefix(r.URL.Path, "/listings/")
+	})))
+}
@@ -0,0 +1,23 @@
+package xhttp
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+)
+
+func ExampleNegotiateContent() {
+	encoders := NewEncoders(JSONEncoder{}, XMLEncoder{})
+	// register custom encoder
+	encoders.Register(NewEncoder("text/csv", func(v any) ([]byte, error) {
+		var b strings.Builder
+		err := csv.NewWriter(&b).WriteAll(v.([][]string))
+		return []byte(b.String()), err
+	}))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 200 OK using the encoder negotiated from the Accept header
+		OK(w, [][]string{{"id", "title"}, {"1", "flat"}})
+	})
+	http.Handle("/resource", NegotiateContent(encoders)(handler))
+}
@@ -0,0 +1,22 @@
+package xhttp
+
+import (
+	"net/http"
+)
+
+func ExampleWriteProblem() {
+	http.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 404 NotFound problem details document
+		WriteProblem(w, NewProblem(http.StatusNotFound, "listing 123 not found"))
+	})
+}
+
+func ExampleValidationProblem() {
+	http.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 422 UnprocessableEntity and the list of invalid fields
+		WriteProblem(w, ValidationProblem(
+			&FieldError{Field: "price", Message: "must be positive"},
+			&FieldError{Field: "rooms", Message: "is required"},
+		))
+	})
+}
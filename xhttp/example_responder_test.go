@@ -0,0 +1,24 @@
+package xhttp
+
+import (
+	"log"
+	"net/http"
+)
+
+func ExampleNewResponder() {
+	rs := NewResponder(
+		WithEncoder(JSONEncoder{Indent: "  ", DisableHTMLEscape: true}),
+		WithDefaultHeader("Cache-Control", "no-store"),
+		WithEncodeErrorHook(func(err error, body any) {
+			log.Printf("serializing %T: %v", body, err)
+		}),
+		WithEnvelope(func(code int, body any) any {
+			return map[string]any{"data": body}
+		}),
+	)
+
+	http.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		// respond with HTTP 200 OK and {"data": {"value": "success"}} body
+		rs.OK(w, &dummyResponse{Value: "success"})
+	})
+}
@@ -0,0 +1,37 @@
+package xhttp
+
+import (
+	"context"
+	"net/http"
+)
+
+func ExampleRouter() {
+	type getListing struct {
+		ID string `path:"id"`
+	}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				WriteError(w, r, ErrUnauthorized("missing credentials"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	router := NewRouter()
+	api := router.Group("/api/v1")
+	api.Get("/listings/{id}", Handle(func(ctx context.Context, req getListing) (getListing, error) {
+		return req, nil
+	}))
+	api.HandleFunc(http.MethodGet, "/photos/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		OK(w, map[string]string{"path": PathParam(r, "path"), "route": RoutePattern(r)})
+	})
+
+	admin := api.Group("/admin", auth)
+	admin.Delete("/listings/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	_ = http.ListenAndServe(":8080", router)
+}
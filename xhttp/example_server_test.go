@@ -0,0 +1,26 @@
+package xhttp
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+)
+
+func ExampleRun() {
+	var db *sql.DB
+	// e.g. *health.Registry serving the readiness probe
+	var readiness ReadinessFlag
+
+	srv := &http.Server{Addr: ":8080", Handler: http.DefaultServeMux}
+	err := Run(context.Background(), srv,
+		WithReadiness(readiness),
+		WithDrainDelay(5*time.Second),
+		WithShutdownTimeout(20*time.Second),
+		WithShutdownHook(func(ctx context.Context) error { return db.Close() }),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
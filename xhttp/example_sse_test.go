@@ -0,0 +1,38 @@
+package xhttp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func ExampleNewSSE() {
+	type status struct {
+		ListingID string `json:"listingId"`
+		Status    string `json:"status"`
+	}
+	updates := make(chan status)
+
+	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		s, err := NewSSE(w, r, Heartbeat(15*time.Second))
+		if err != nil {
+			InternalServerError(w, nil)
+			return
+		}
+		defer s.Close()
+
+		// resume the stream after the last event received by the client
+		seq, _ := strconv.Atoi(LastEventID(r))
+		for {
+			select {
+			case <-s.Done():
+				return
+			case u := <-updates:
+				seq++
+				if err := s.Send(Event{ID: strconv.Itoa(seq), Name: "status", Data: u}); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
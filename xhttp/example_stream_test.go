@@ -0,0 +1,28 @@
+package xhttp
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+func ExampleStreamJSONLines() {
+	http.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		items := make(chan *dummyResponse)
+		go func() {
+			defer close(items)
+			for _, v := range []string{"a", "b", "c"} {
+				select {
+				case <-r.Context().Done():
+					return
+				case items <- &dummyResponse{Value: v}:
+				}
+			}
+		}()
+
+		// stream items as application/x-ndjson flushing every 500 items or every 2 seconds
+		if err := StreamJSONLines(w, r, items, FlushEvery(500), FlushInterval(2*time.Second)); err != nil {
+			log.Printf("streaming export: %v", err)
+		}
+	})
+}
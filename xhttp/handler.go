@@ -0,0 +1,173 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/httpbody"
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
+)
+
+// ErrorMapper maps an error returned by the typed handler to the HTTP status code of the response.
+type ErrorMapper func(err error) int
+
+// PathParamFunc returns the value of the path parameter name of the request r, or an
+// empty string if the parameter is not present.
+type PathParamFunc func(r *http.Request, name string) string
+
+// HandlerOption configures the http.Handler returned by Handle.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	status      int
+	errorMapper ErrorMapper
+	pathParam   PathParamFunc
+	responder   *Responder
+	maxBodySize int64
+}
+
+// WithStatus sets the HTTP status code of the successful responses, defaults to HTTP 200 StatusOK.
+func WithStatus(code int) HandlerOption {
+	return func(c *handlerConfig) {
+		c.status = code
+	}
+}
+
+// WithErrorMapper sets the ErrorMapper used to map handler errors to HTTP status codes,
+// defaults to StatusCode.
+func WithErrorMapper(mapper ErrorMapper) HandlerOption {
+	return func(c *handlerConfig) {
+		c.errorMapper = mapper
+	}
+}
+
+// WithPathParams sets the function used to read the path parameters of the request, defaults
+// to PathParam reading the parameters of the Router. Use it with the other routers.
+func WithPathParams(f PathParamFunc) HandlerOption {
+	return func(c *handlerConfig) {
+		c.pathParam = f
+	}
+}
+
+// WithResponder sets the Responder used to write the successful responses, defaults to DefaultResponder.
+func WithResponder(rs *Responder) HandlerOption {
+	return func(c *handlerConfig) {
+		c.responder = rs
+	}
+}
+
+// WithMaxBodySize limits the size of the request body to n bytes. Larger requests are
+// replied with an HTTP 413 StatusRequestEntityTooLarge problem details document.
+func WithMaxBodySize(n int64) HandlerOption {
+	return func(c *handlerConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// Handle returns an http.Handler that binds the request into Req, calls the supplied function
+// and writes the returned Resp using the Responder, see WithResponder. A nil Resp pointer
+// results in a response without a body.
+//
+// The request body (if present) is bound using httpbody.BindJSON. Afterwards, the exported
+// fields of Req tagged with `query:"name"` are filled from the URL query parameters and the
+// fields tagged with `path:"name"` are filled from the path parameters, see WithPathParams
+// and BindValues for the supported field types. If the binding fails, the request is replied
+// with an HTTP 400 StatusBadRequest problem details document, or an HTTP 413
+// StatusRequestEntityTooLarge if the body exceeds the limit, see WithMaxBodySize.
+//
+// The bound request is validated using xvalidate.Validate. Invalid fields are replied with an
+// HTTP 422 StatusUnprocessableEntity problem details document listing the fields.
+//
+// Errors returned by fn are mapped to the HTTP status codes using the ErrorMapper, see
+// WithErrorMapper, and written as problem details documents, see WriteError.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandlerOption) http.Handler {
+	cfg := &handlerConfig{
+		status:      http.StatusOK,
+		errorMapper: StatusCode,
+		pathParam:   PathParam,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := bindRequest[Req](r, cfg)
+		if err != nil {
+			status := http.StatusBadRequest
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			writeError(w, r, err, status)
+			return
+		}
+		if err = xvalidate.Validate(req, xvalidate.WithNameTags("json", "query", "path")); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			writeError(w, r, err, cfg.errorMapper(err))
+			return
+		}
+		rs := cfg.responder
+		if rs == nil {
+			rs = DefaultResponder
+		}
+		if isNilPointer(resp) {
+			rs.WriteResponse(w, cfg.status, nil)
+			return
+		}
+		rs.WriteResponse(w, cfg.status, resp)
+	})
+}
+
+// bindRequest binds the request body, query and path parameters into a new Req value.
+func bindRequest[Req any](r *http.Request, cfg *handlerConfig) (req Req, err error) {
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+		if req, err = httpbody.BindJSON[Req](r.Body, httpbody.WithMaxBytes(cfg.maxBodySize)); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+	}
+
+	dst := reflect.ValueOf(&req).Elem()
+	if !hasStructFields(dst.Type()) {
+		return req, nil
+	}
+	query := r.URL.Query()
+	if err = bindValues(dst, "query", func(name string) []string { return query[name] }); err != nil {
+		return req, fmt.Errorf("invalid query parameters: %w", err)
+	}
+	if cfg.pathParam != nil {
+		err = bindValues(dst, "path", func(name string) []string {
+			if v := cfg.pathParam(r, name); v != "" {
+				return []string{v}
+			}
+			return nil
+		})
+		if err != nil {
+			return req, fmt.Errorf("invalid path parameters: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// hasStructFields reports whether typ is a struct or a pointer to a struct.
+func hasStructFields(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}
+
+// isNilPointer reports whether v is nil or holds a nil pointer.
+func isNilPointer(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
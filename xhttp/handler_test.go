@@ -0,0 +1,203 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type listingRequest struct {
+	ID     string   `path:"id"`
+	Title  string   `json:"title" validate:"max=20"`
+	Rooms  *int     `query:"rooms" validate:"min=1"`
+	Tags   []string `query:"tag"`
+	Draft  bool     `query:"draft"`
+	hidden string   `query:"hidden"`
+}
+
+type listingResponse struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Rooms int    `json:"rooms"`
+	Tags  string `json:"tags"`
+	Draft bool   `json:"draft"`
+}
+
+func TestHandle(t *testing.T) {
+	pathParams := WithPathParams(func(r *http.Request, name string) string {
+		if name == "id" {
+			return strings.TrimPrefix(r.URL.Path, "/listings/")
+		}
+		return ""
+	})
+	handler := func(ctx context.Context, req *listingRequest) (*listingResponse, error) {
+		switch req.ID {
+		case "missing":
+			return nil, NewProblem(http.StatusNotFound, "listing missing not found")
+		case "failing":
+			return nil, errors.New("connecting to db: connection refused")
+		case "unavailable":
+			return nil, ErrNotFound("").Wrap(errors.New("pq: password=secret host=db"))
+		case "empty":
+			return nil, nil
+		}
+		resp := &listingResponse{ID: req.ID, Title: req.Title, Tags: strings.Join(req.Tags, ","), Draft: req.Draft}
+		if req.Rooms != nil {
+			resp.Rooms = *req.Rooms
+		}
+		return resp, nil
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		opts       []HandlerOption
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should bind body, query and path parameters",
+			method:     http.MethodPut,
+			target:     "/listings/123?rooms=3&tag=a&tag=b&draft=true&hidden=x",
+			body:       `{"title":"flat"}`,
+			opts:       []HandlerOption{pathParams},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"123","title":"flat","rooms":3,"tags":"a,b","draft":true}`,
+		},
+		{
+			name:       "should bind request without body",
+			method:     http.MethodGet,
+			target:     "/listings/123",
+			opts:       []HandlerOption{pathParams},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"123","title":"","rooms":0,"tags":"","draft":false}`,
+		},
+		{
+			name:       "should not bind path parameters without path params function",
+			method:     http.MethodPost,
+			target:     "/listings",
+			body:       `{"title":"flat"}`,
+			opts:       []HandlerOption{WithStatus(http.StatusCreated)},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"","title":"flat","rooms":0,"tags":"","draft":false}`,
+		},
+		{
+			name:       "should respond with payload too large if body exceeds limit",
+			method:     http.MethodPost,
+			target:     "/listings",
+			body:       `{"title":"flat with a view on the old town"}`,
+			opts:       []HandlerOption{WithMaxBodySize(16)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody: `{"title":"Request Entity Too Large","status":413,` +
+				`"detail":"request body exceeds the limit of 16 bytes","instance":"/listings"}`,
+		},
+		{
+			name:       "should respond with bad request if body is malformed",
+			method:     http.MethodPost,
+			target:     "/listings",
+			body:       `{"title":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"title":"Bad Request","status":400,` +
+				`"detail":"invalid request body: unmarshaling body: unexpected end of JSON input","instance":"/listings"}`,
+		},
+		{
+			name:       "should respond with bad request and field errors if query is invalid",
+			method:     http.MethodGet,
+			target:     "/listings?rooms=three",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"detail":"rooms: invalid integer value \"three\"",` +
+				`"errors":[{"field":"rooms","message":"invalid integer value \"three\""}],` +
+				`"instance":"/listings","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:       "should respond with unprocessable entity if request is invalid",
+			method:     http.MethodPost,
+			target:     "/listings?rooms=0",
+			body:       `{"title":"flat with a view on the old town"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"detail":"title: must be at most 20 characters long; rooms: must be at least 1",` +
+				`"errors":[{"field":"title","message":"must be at most 20 characters long"},{"field":"rooms","message":"must be at least 1"}],` +
+				`"instance":"/listings","status":422,"title":"Unprocessable Entity"}`,
+		},
+		{
+			name:       "should map errors using error mapper",
+			method:     http.MethodGet,
+			target:     "/listings/missing",
+			opts:       []HandlerOption{pathParams},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"title":"Not Found","status":404,"detail":"listing missing not found","instance":"/listings/missing"}`,
+		},
+		{
+			name:       "should hide internal error details",
+			method:     http.MethodGet,
+			target:     "/listings/failing",
+			opts:       []HandlerOption{pathParams},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"title":"Internal Server Error","status":500,"detail":"Internal Server Error","instance":"/listings/failing"}`,
+		},
+		{
+			name:   "should use custom error mapper",
+			method: http.MethodGet,
+			target: "/listings/failing",
+			opts: []HandlerOption{pathParams, WithErrorMapper(func(err error) int {
+				return http.StatusServiceUnavailable
+			})},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"title":"Service Unavailable","status":503,"detail":"Service Unavailable","instance":"/listings/failing"}`,
+		},
+		{
+			name:   "should hide cause of client error mapped to server error",
+			method: http.MethodGet,
+			target: "/listings/unavailable",
+			opts: []HandlerOption{pathParams, WithErrorMapper(func(err error) int {
+				return http.StatusInternalServerError
+			})},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"title":"Internal Server Error","status":500,"detail":"Internal Server Error","instance":"/listings/unavailable"}`,
+		},
+		{
+			name:       "should respond without body if response is nil",
+			method:     http.MethodDelete,
+			target:     "/listings/empty",
+			opts:       []HandlerOption{pathParams, WithStatus(http.StatusNoContent)},
+			wantStatus: http.StatusNoContent,
+			wantBody:   ``,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			r := httptest.NewRequest(tt.method, tt.target, body)
+			w := httptest.NewRecorder()
+			Handle(handler, tt.opts...).ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Handle() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Handle() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandle_NonStructRequest(t *testing.T) {
+	handler := Handle(func(ctx context.Context, req []int) (int, error) {
+		return len(req), nil
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sum?a=1", strings.NewReader(`[1,2,3]`)))
+
+	if got := w.Body.String(); got != "3" {
+		t.Errorf("Handle() = body got %s, want %s", got, "3")
+	}
+}
@@ -0,0 +1,22 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+func ExampleRegistry() {
+	var db *sql.DB
+
+	registry := NewRegistry(WithCacheTTL(5 * time.Second))
+	registry.Register("postgres", db.PingContext, WithTimeout(time.Second))
+	registry.Register("search-index", func(ctx context.Context) error {
+		// the service keeps working without the search index, with reduced functionality
+		return nil
+	}, NonCritical())
+
+	http.Handle("/healthz", registry.LivenessHandler())
+	http.Handle("/readyz", registry.ReadinessHandler())
+}
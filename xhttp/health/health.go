@@ -0,0 +1,292 @@
+// Package health provides a registry of health checks exposed as liveness and readiness
+// endpoints built on top of the xhttp package.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// Status is the status of a check or of the whole service.
+type Status string
+
+const (
+	// StatusUp means that the check passed, or all the checks passed.
+	StatusUp Status = "up"
+	// StatusDegraded means that some non-critical checks failed.
+	StatusDegraded Status = "degraded"
+	// StatusDown means that the check failed, or some critical checks failed.
+	StatusDown Status = "down"
+)
+
+// CheckFunc checks the health of a component, e.g. pings the database. It returns an error
+// if the component is not healthy. It should return as soon as ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the result of a single check.
+type CheckResult struct {
+	// Status is either StatusUp or StatusDown.
+	Status Status `json:"status"`
+	// Critical reports whether the failure of the check makes the service down.
+	Critical bool `json:"critical"`
+	// Latency is the duration of the check, serialized as a duration string, e.g. "1.5ms".
+	Latency time.Duration `json:"-"`
+	// Error is the error message of the failed check.
+	Error string `json:"error,omitempty"`
+	// CheckedAt is the time of the check, cached results keep their original time.
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	type result CheckResult
+	return json.Marshal(struct {
+		result
+		Latency string `json:"latency"`
+	}{result: result(r), Latency: r.Latency.String()})
+}
+
+// Report is the result of all the checks of a liveness or readiness probe.
+type Report struct {
+	// Status is the overall status: StatusDown if any critical check failed or the registry
+	// is not ready, StatusDegraded if any non-critical check failed, StatusUp otherwise.
+	Status Status `json:"status"`
+	// Checks holds the results of the checks by their names.
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckOption configures a check registered with Registry.Register.
+type CheckOption func(*check)
+
+// WithTimeout sets the timeout of the check, defaults to the registry default timeout.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// NonCritical marks the check as non-critical: its failure degrades the service, but
+// does not make it down.
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// Liveness includes the check in the liveness probe. Liveness checks should only cover the
+// state of the process itself, since a failed liveness probe usually restarts the service.
+func Liveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// RegistryOption configures the Registry.
+type RegistryOption func(*Registry)
+
+// WithCacheTTL sets how long the check results are cached, defaults to 1s. A zero TTL
+// disables the caching.
+func WithCacheTTL(ttl time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.ttl = ttl
+	}
+}
+
+// WithDefaultTimeout sets the timeout of the checks registered without WithTimeout,
+// defaults to 2s.
+func WithDefaultTimeout(d time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+// Registry holds the health checks of the service components.
+//
+// Registry is safe for concurrent use.
+type Registry struct {
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu       sync.RWMutex
+	checks   []*check
+	notReady atomic.Bool
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+	liveness bool
+
+	// mu serializes the runs of the check, so that the concurrent probes share the cached result.
+	mu     sync.Mutex
+	result CheckResult
+	cached bool
+}
+
+// NewRegistry returns a new, ready Registry without any checks.
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{
+		ttl:     time.Second,
+		timeout: 2 * time.Second,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds the named check to the registry. Checks are critical and included in the
+// readiness probe only, unless configured otherwise with the options.
+func (r *Registry) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	c := &check{name: name, fn: fn, timeout: r.timeout, critical: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, c)
+}
+
+// SetReady sets whether the service is ready to receive traffic. A registry that is not
+// ready reports StatusDown in the readiness probe without running the checks, e.g. while
+// the server is shutting down. New registries are ready.
+func (r *Registry) SetReady(ready bool) {
+	r.notReady.Store(!ready)
+}
+
+// Liveness runs the liveness checks and returns the report.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+// Readiness runs all the checks and returns the report.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	if r.notReady.Load() {
+		return Report{Status: StatusDown}
+	}
+	return r.run(ctx, false)
+}
+
+// LivenessHandler returns an http.Handler that writes the Liveness report, with an
+// HTTP 503 StatusServiceUnavailable if the status is StatusDown.
+func (r *Registry) LivenessHandler() http.Handler {
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler returns an http.Handler that writes the Readiness report, with an
+// HTTP 503 StatusServiceUnavailable if the status is StatusDown.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return reportHandler(r.Readiness)
+}
+
+func reportHandler(probe func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == StatusDown {
+			xhttp.WriteResponse(w, http.StatusServiceUnavailable, report)
+			return
+		}
+		xhttp.OK(w, report)
+	})
+}
+
+// run runs the checks concurrently, only the liveness checks if liveness is true.
+func (r *Registry) run(ctx context.Context, liveness bool) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		if !liveness || c.liveness {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.result(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+		switch {
+		case res.Status == StatusUp:
+		case res.Critical:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// result returns the cached result of the check, or runs the check if the result expired.
+func (r *Registry) result(ctx context.Context, c *check) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached && r.now().Sub(c.result.CheckedAt) < r.ttl {
+		return c.result
+	}
+
+	start := r.now()
+	err := runCheck(ctx, c)
+	res := CheckResult{
+		Status:    StatusUp,
+		Critical:  c.critical,
+		Latency:   r.now().Sub(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	// results of the checks interrupted by the canceled probe are not cached
+	c.result, c.cached = res, ctx.Err() == nil
+	return res
+}
+
+// runCheck runs the check with its timeout. It returns as soon as the timeout expires, even
+// if the check ignores the context.
+func runCheck(ctx context.Context, c *check) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("check timed out after %s", c.timeout)
+		}
+		return ctx.Err()
+	}
+}
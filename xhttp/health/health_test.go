@@ -0,0 +1,189 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var checkedAt = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestRegistry(opts ...RegistryOption) *Registry {
+	r := NewRegistry(opts...)
+	r.now = func() time.Time { return checkedAt }
+	return r
+}
+
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("connection refused") }
+
+func TestRegistry_Readiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		register   func(r *Registry)
+		wantStatus Status
+	}{
+		{
+			name:       "should be up without checks",
+			register:   func(r *Registry) {},
+			wantStatus: StatusUp,
+		},
+		{
+			name: "should be up if all checks pass",
+			register: func(r *Registry) {
+				r.Register("db", up)
+				r.Register("cache", up, NonCritical())
+			},
+			wantStatus: StatusUp,
+		},
+		{
+			name: "should be degraded if non-critical check fails",
+			register: func(r *Registry) {
+				r.Register("db", up)
+				r.Register("cache", down, NonCritical())
+			},
+			wantStatus: StatusDegraded,
+		},
+		{
+			name: "should be down if critical check fails",
+			register: func(r *Registry) {
+				r.Register("db", down)
+				r.Register("cache", down, NonCritical())
+			},
+			wantStatus: StatusDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry()
+			tt.register(r)
+
+			if got := r.Readiness(context.Background()).Status; got != tt.wantStatus {
+				t.Errorf("Readiness() = status got %q, want %q", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRegistry_Liveness(t *testing.T) {
+	r := newTestRegistry()
+	r.Register("db", down)
+	r.Register("goroutines", up, Liveness())
+
+	report := r.Liveness(context.Background())
+
+	if report.Status != StatusUp {
+		t.Errorf("Liveness() = status got %q, want %q", report.Status, StatusUp)
+	}
+	if _, ok := report.Checks["db"]; ok || len(report.Checks) != 1 {
+		t.Errorf("Liveness() = expected only liveness checks got %v", report.Checks)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRegistry()
+	r.Register("slow", func(ctx context.Context) error {
+		<-release // ignores the context
+		return nil
+	}, WithTimeout(10*time.Millisecond))
+
+	res := r.Readiness(context.Background()).Checks["slow"]
+
+	if res.Status != StatusDown || res.Error != "check timed out after 10ms" {
+		t.Errorf("Readiness() = got %+v, want timed out check", res)
+	}
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	now := checkedAt
+	var calls atomic.Int32
+	r := NewRegistry(WithCacheTTL(time.Minute))
+	r.now = func() time.Time { return now }
+	r.Register("db", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	r.Readiness(context.Background())
+	now = now.Add(30 * time.Second)
+	r.Readiness(context.Background())
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Readiness() = check calls got %d, want %d", got, 1)
+	}
+
+	now = now.Add(30 * time.Second)
+	r.Readiness(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Readiness() = check calls got %d, want %d", got, 2)
+	}
+}
+
+func TestRegistry_Panic(t *testing.T) {
+	r := newTestRegistry()
+	r.Register("broken", func(ctx context.Context) error { panic("boom") })
+
+	res := r.Readiness(context.Background()).Checks["broken"]
+
+	if res.Status != StatusDown || res.Error != "check panicked: boom" {
+		t.Errorf("Readiness() = got %+v, want panicked check", res)
+	}
+}
+
+func TestRegistry_Handlers(t *testing.T) {
+	r := newTestRegistry()
+	r.Register("db", down)
+	r.Register("cache", up, NonCritical(), Liveness())
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		ready      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should report liveness",
+			handler:    r.LivenessHandler(),
+			ready:      true,
+			wantStatus: http.StatusOK,
+			wantBody: `{"status":"up","checks":{"cache":{"status":"up","critical":false,` +
+				`"checkedAt":"2023-05-01T12:00:00Z","latency":"0s"}}}`,
+		},
+		{
+			name:       "should report failed readiness",
+			handler:    r.ReadinessHandler(),
+			ready:      true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: `{"status":"down","checks":{` +
+				`"cache":{"status":"up","critical":false,"checkedAt":"2023-05-01T12:00:00Z","latency":"0s"},` +
+				`"db":{"status":"down","critical":true,"error":"connection refused","checkedAt":"2023-05-01T12:00:00Z","latency":"0s"}}}`,
+		},
+		{
+			name:       "should report not ready registry",
+			handler:    r.ReadinessHandler(),
+			ready:      false,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"status":"down"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.SetReady(tt.ready)
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Handler() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Handler() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -3,8 +3,6 @@
 package xhttp
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
@@ -13,55 +11,53 @@ import (
 // writes are done to w.
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func WriteResponse(w http.ResponseWriter, code int, body any) {
-	if body == nil {
-		w.WriteHeader(code)
-		return
-	}
-	b, err := json.Marshal(body)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to serialize body: %v", err), http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(code)
-	w.Write(b)
+// If the serialization fails, the request is replied with an HTTP 500 StatusInternalServerError
+// problem details document instead, see WriteProblem.
+//
+// If the handler is wrapped with NegotiateContent middleware, the body is serialized
+// using the negotiated Encoder instead of JSON.
+//
+// Options apply to the single response, e.g. Conditional enables the conditional
+// request handling using the ETag and Last-Modified headers.
+//
+// WriteResponse uses the DefaultResponder, see Responder to customize the serialization.
+func WriteResponse(w http.ResponseWriter, code int, body any, opts ...ResponseOption) {
+	DefaultResponder.WriteResponse(w, code, body, opts...)
 }
 
 // Created replies to the request with an HTTP 201 StatusCreated and a supplied body (if body was provided).
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func Created(w http.ResponseWriter, body any) {
-	WriteResponse(w, http.StatusCreated, body)
+func Created(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	WriteResponse(w, http.StatusCreated, body, opts...)
 }
 
 // OK replies to the request with an HTTP 200 StatusOK and a supplied body (if body was provided).
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func OK(w http.ResponseWriter, body any) {
-	WriteResponse(w, http.StatusOK, body)
+func OK(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	WriteResponse(w, http.StatusOK, body, opts...)
 }
 
 // UnprocessableEntity replies to the request with an HTTP 422 StatusUnprocessableEntity and a
 // supplied error body (if body was provided).
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func UnprocessableEntity(w http.ResponseWriter, body any) {
-	WriteResponse(w, http.StatusUnprocessableEntity, body)
+func UnprocessableEntity(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	WriteResponse(w, http.StatusUnprocessableEntity, body, opts...)
 }
 
 // BadRequest replies to the request with an HTTP 400 StatusBadRequest and a supplied body (if body was provided).
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func BadRequest(w http.ResponseWriter, body any) {
-	WriteResponse(w, http.StatusBadRequest, body)
+func BadRequest(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	WriteResponse(w, http.StatusBadRequest, body, opts...)
 }
 
 // InternalServerError replies to the request with an HTTP 500 StatusInternalServerError and a
 // supplied body (if body was provided).
 //
 // if body is not nil, it should be a value that can be serialized using json.Marshal.
-func InternalServerError(w http.ResponseWriter, body any) {
-	WriteResponse(w, http.StatusInternalServerError, body)
+func InternalServerError(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	WriteResponse(w, http.StatusInternalServerError, body, opts...)
 }
@@ -195,9 +195,9 @@ func TestResponse(t *testing.T) {
 		}
 	})
 
-	t.Run("should return error if body marshal failed", func(t *testing.T) {
+	t.Run("should return problem if body marshal failed", func(t *testing.T) {
 		status := http.StatusInternalServerError
-		message := "failed to serialize body: json: unsupported value: NaN\n"
+		message := `{"title":"Internal Server Error","status":500,"detail":"failed to serialize response body"}`
 		w := &writerMock{
 			WriteMock: func(body []byte) (i int, e error) {
 				if got := string(body); got != message {
@@ -212,9 +212,9 @@ func TestResponse(t *testing.T) {
 			},
 			headers: map[string][]string{},
 		}
-		WriteResponse(w, status, math.NaN())
+		WriteResponse(w, http.StatusOK, math.NaN())
 		headers := map[string][]string{
-			"Content-Type":           {"text/plain; charset=utf-8"},
+			"Content-Type":           {"application/problem+json"},
 			"X-Content-Type-Options": {"nosniff"},
 		}
 		if got := w.headers; !reflect.DeepEqual(got, headers) {
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// AccessLogOption configures the AccessLog middleware.
+type AccessLogOption func(*accessLogConfig)
+
+type accessLogConfig struct {
+	route func(r *http.Request) string
+	level func(status int) slog.Level
+}
+
+// WithRouteFunc sets the function that returns the route template of the request (e.g.
+// "/listings/{id}") logged as the route attribute, defaults to xhttp.RoutePattern.
+func WithRouteFunc(route func(r *http.Request) string) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.route = route
+	}
+}
+
+// WithLevelFunc sets the function that returns the log level for the response status,
+// by default 5xx responses are logged at slog.LevelError and any other at slog.LevelInfo.
+func WithLevelFunc(level func(status int) slog.Level) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.level = level
+	}
+}
+
+// AccessLog returns middleware that logs every request once it has been handled, with the
+// method, path, route, status, number of bytes written, latency and request ID attributes.
+//
+// If logger is nil, slog.Default is used.
+func AccessLog(logger *slog.Logger, opts ...AccessLogOption) func(http.Handler) http.Handler {
+	cfg := &accessLogConfig{
+		route: xhttp.RoutePattern,
+		level: func(status int) slog.Level {
+			if status >= http.StatusInternalServerError {
+				return slog.LevelError
+			}
+			return slog.LevelInfo
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrap(w)
+			r = r.WithContext(xhttp.ContextWithRoute(r.Context()))
+			next.ServeHTTP(rw, r)
+
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.Status()),
+				slog.Int64("bytes", rw.bytes),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if cfg.route != nil {
+				if route := cfg.route(r); route != "" {
+					attrs = append(attrs, slog.String("route", route))
+				}
+			}
+			id := RequestIDFromContext(r.Context())
+			if id == "" {
+				// RequestID middleware might be applied after the access log
+				id = w.Header().Get(HeaderRequestID)
+			}
+			if id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			l.LogAttrs(r.Context(), cfg.level(rw.Status()), "http request", attrs...)
+		})
+	}
+}
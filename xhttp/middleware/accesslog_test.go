@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func TestAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	handler := Chain(
+		RequestID(WithRequestIDGenerator(func() string { return "req-1" })),
+		AccessLog(logger, WithRouteFunc(func(r *http.Request) string { return "/listings/{id}" })),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/listings/1", nil))
+
+	var got map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &got); err != nil {
+		t.Fatalf("AccessLog() = invalid log entry %s: %v", logs.String(), err)
+	}
+	want := map[string]any{
+		"level":      "ERROR",
+		"msg":        "http request",
+		"method":     "GET",
+		"path":       "/listings/1",
+		"route":      "/listings/{id}",
+		"status":     float64(http.StatusServiceUnavailable),
+		"bytes":      float64(11),
+		"request_id": "req-1",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("AccessLog() = attribute %s got %v, want %v", k, got[k], v)
+		}
+	}
+	if _, ok := got["latency"]; !ok {
+		t.Errorf("AccessLog() = expected latency attribute")
+	}
+}
+
+func TestAccessLog_Defaults(t *testing.T) {
+	var logs bytes.Buffer
+	handler := AccessLog(slog.New(slog.NewJSONHandler(&logs, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/listings", nil))
+
+	var got map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &got); err != nil {
+		t.Fatalf("AccessLog() = invalid log entry %s: %v", logs.String(), err)
+	}
+	if got["level"] != "INFO" || got["status"] != float64(http.StatusOK) {
+		t.Errorf("AccessLog() = got level %v and status %v, want INFO and 200", got["level"], got["status"])
+	}
+	for _, k := range []string{"route", "request_id"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("AccessLog() = expected %s attribute to be omitted", k)
+		}
+	}
+}
+
+func TestAccessLog_Router(t *testing.T) {
+	var logs bytes.Buffer
+	router := xhttp.NewRouter()
+	router.Get("/listings/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler := AccessLog(slog.New(slog.NewJSONHandler(&logs, nil)))(router)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/listings/1", nil))
+
+	var got map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &got); err != nil {
+		t.Fatalf("AccessLog() = invalid log entry %s: %v", logs.String(), err)
+	}
+	if got["route"] != "/listings/{id}" {
+		t.Errorf("AccessLog() = attribute route got %v, want %v", got["route"], "/listings/{id}")
+	}
+}
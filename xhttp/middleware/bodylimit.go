@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// BodyLimit returns middleware that limits the size of the request bodies to n bytes.
+//
+// Requests declaring a larger Content-Length are replied with an HTTP 413
+// StatusRequestEntityTooLarge problem details document right away. Bodies of the other
+// requests are wrapped using http.MaxBytesReader, so reading past the limit fails with
+// *http.MaxBytesError, which xhttp.WriteError writes as an HTTP 413 as well.
+func BodyLimit(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				xhttp.WriteError(w, r, &http.MaxBytesError{Limit: n})
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func TestBodyLimit(t *testing.T) {
+	type listing struct {
+		Title string `json:"title"`
+	}
+	handler := xhttp.Handle(func(ctx context.Context, req listing) (listing, error) {
+		return req, nil
+	})
+	tooLarge := `{"title":"Request Entity Too Large","status":413,"detail":"request body exceeds the limit of 16 bytes","instance":"/listings"}`
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		chunked    bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should pass body within limit",
+			body:       strings.NewReader(`{"title":"flat"}`),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"title":"flat"}`,
+		},
+		{
+			name:       "should reject body with too large content length",
+			body:       strings.NewReader(`{"title":"flat with a view"}`),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   tooLarge,
+		},
+		{
+			name:       "should reject too large body of unknown length",
+			body:       strings.NewReader(`{"title":"flat with a view"}`),
+			chunked:    true,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   tooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/listings", tt.body)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			BodyLimit(16)(handler).ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("BodyLimit() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("BodyLimit() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestBodyLimit_Error(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("a", 32)))
+	r.ContentLength = -1
+	var readErr error
+	BodyLimit(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	var mbe *http.MaxBytesError
+	if !errors.As(readErr, &mbe) {
+		t.Errorf("ReadAll() error = %v, want *http.MaxBytesError", readErr)
+	}
+}
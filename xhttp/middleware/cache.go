@@ -0,0 +1,438 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderCache is the header reporting whether the response has been served from the cache,
+// one of "HIT", "STALE" or "MISS".
+const HeaderCache = "X-Cache"
+
+// CacheStats are the statistics of a ResponseCache.
+type CacheStats struct {
+	// Hits is the number of the responses served fresh from the cache.
+	Hits uint64
+	// StaleHits is the number of the stale responses served while being revalidated.
+	StaleHits uint64
+	// Misses is the number of the responses not found in the cache.
+	Misses uint64
+	// Evictions is the number of the entries evicted to make room for the new ones.
+	Evictions uint64
+	// Entries is the number of the cached responses.
+	Entries int
+}
+
+// ResponseCache is an in-process cache of the HTTP responses bounded by the number of
+// entries. The least recently used entries are evicted first.
+//
+// It can be shared by multiple Cache middleware, e.g. mounted on different routes.
+// ResponseCache is safe for concurrent use.
+type ResponseCache struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List // front is the most recently used *cacheEntry
+	entries map[string]*list.Element
+	varies  map[string]*cacheVary // by the base key of the entries
+	stats   CacheStats
+}
+
+// cacheVary are the request headers named by the Vary header of the responses of a base key,
+// i.e. of a method and a URL. They are kept while any entry of the base key is cached.
+type cacheVary struct {
+	headers []string
+	entries int
+}
+
+type cacheEntry struct {
+	key    string
+	base   string
+	status int
+	header http.Header
+	body   []byte
+
+	stored       time.Time
+	freshUntil   time.Time
+	staleUntil   time.Time
+	revalidating bool
+}
+
+// NewResponseCache returns a new ResponseCache holding up to maxEntries responses.
+func NewResponseCache(maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		maxEntries: maxEntries,
+		now:        time.Now,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		varies:     make(map[string]*cacheVary),
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.lru.Len()
+	return s
+}
+
+// Purge removes all the cached responses, e.g. after the cached dictionaries have changed.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	clear(c.entries)
+	clear(c.varies)
+}
+
+// cacheState is the state of a cache lookup.
+type cacheState int
+
+const (
+	cacheMiss cacheState = iota
+	cacheHit
+	cacheStale
+)
+
+// lookup returns the key of the request, including the headers the cached responses of the
+// base key vary by, and its entry and state. For stale entries, it reports whether the caller
+// should revalidate the entry, so only one revalidation runs at a time.
+func (c *ResponseCache) lookup(r *http.Request, base string) (key string, e *cacheEntry, state cacheState, revalidate bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key = base
+	if v, ok := c.varies[base]; ok {
+		key = varyKey(r, base, v.headers)
+	}
+	now := c.now()
+	el, ok := c.entries[key]
+	if ok {
+		e = el.Value.(*cacheEntry)
+		switch {
+		case now.Before(e.freshUntil):
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return key, e, cacheHit, false
+		case now.Before(e.staleUntil):
+			c.lru.MoveToFront(el)
+			c.stats.StaleHits++
+			revalidate = !e.revalidating
+			e.revalidating = true
+			return key, e, cacheStale, revalidate
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	return key, nil, cacheMiss, false
+}
+
+// store adds the entry to the cache, replacing the present entry of its key. The entry
+// replaces the request headers the responses of its base key vary by.
+func (c *ResponseCache) store(e *cacheEntry, vary []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	v, ok := c.varies[e.base]
+	if !ok {
+		v = &cacheVary{}
+		c.varies[e.base] = v
+	}
+	if !slices.Equal(v.headers, vary) {
+		// the entries stored under the keys of the previous headers are not found anymore,
+		// they are evicted eventually
+		v.headers = vary
+	}
+	v.entries++
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// revalidated marks the revalidation of the entry of the key as finished.
+func (c *ResponseCache) revalidated(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).revalidating = false
+	}
+}
+
+// remove removes the element from the cache, it must be called with mu held.
+func (c *ResponseCache) remove(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.key)
+	if v, ok := c.varies[e.base]; ok {
+		if v.entries--; v.entries <= 0 {
+			delete(c.varies, e.base)
+		}
+	}
+}
+
+// CacheOption configures the Cache middleware.
+type CacheOption func(*cacheConfig)
+
+type cacheConfig struct {
+	vary   []string
+	maxAge time.Duration
+}
+
+// WithVaryHeaders sets the request headers the responses vary by, e.g. "Accept-Language".
+// Their values are a part of the cache key.
+func WithVaryHeaders(headers ...string) CacheOption {
+	return func(c *cacheConfig) {
+		c.vary = headers
+	}
+}
+
+// WithDefaultMaxAge sets the time the responses without the max-age or s-maxage
+// Cache-Control directives are fresh for. By default such responses are not cached.
+func WithDefaultMaxAge(d time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.maxAge = d
+	}
+}
+
+// Cache returns middleware that serves the GET and HEAD requests from the supplied
+// ResponseCache.
+//
+// The responses are cached by the request method, path, query parameters in any order and
+// the values of the headers set by WithVaryHeaders and of the headers named by the Vary
+// header of the response, e.g. Accept-Encoding set by Compress. The responses with Vary: *
+// are not cached. The requests with the Authorization or the Cookie header are not cached,
+// as their responses are likely personalized.
+//
+// The Cache-Control header set by the handler controls the caching: s-maxage or max-age set
+// the time the response is fresh for, and no-store, no-cache or private prevent caching it.
+// With stale-while-revalidate, the stale response is served for the given time while it is
+// refreshed by calling the handler in the background. Only the successful and a few other
+// cacheable status codes are cached, e.g. HTTP 404 StatusNotFound, and neither are the
+// responses setting cookies.
+//
+// Every response gets the X-Cache header, the responses served from the cache get the Age
+// header as well.
+func Cache(c *ResponseCache, opts ...CacheOption) func(http.Handler) http.Handler {
+	cfg := &cacheConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+				r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			base := cacheKey(r, cfg.vary)
+
+			key, e, state, revalidate := c.lookup(r, base)
+			switch state {
+			case cacheHit:
+				writeCached(w, e, "HIT", c.now())
+				return
+			case cacheStale:
+				if revalidate {
+					go c.revalidate(next, r.Clone(context.WithoutCancel(r.Context())), base, key, cfg)
+				}
+				writeCached(w, e, "STALE", c.now())
+				return
+			}
+
+			w.Header().Set(HeaderCache, "MISS")
+			rw := newRecordingWriter(w)
+			next.ServeHTTP(rw, r)
+			c.storeRecorded(r, base, rw, cfg)
+		})
+	}
+}
+
+// revalidate refreshes the entry of the key by calling the handler with r.
+func (c *ResponseCache) revalidate(next http.Handler, r *http.Request, base, key string, cfg *cacheConfig) {
+	defer c.revalidated(key)
+	// the panics are not propagated to the serving goroutine, they would crash the server
+	defer func() { _ = recover() }()
+
+	rw := newRecordingWriter(&discardWriter{header: make(http.Header)})
+	next.ServeHTTP(rw, r)
+	c.storeRecorded(r, base, rw, cfg)
+}
+
+// storeRecorded stores the recorded response of the request, unless it is not cacheable.
+func (c *ResponseCache) storeRecorded(r *http.Request, base string, rw *recordingWriter, cfg *cacheConfig) {
+	status, header, body := rw.result()
+	vary, ok := varyHeaders(header)
+	if !ok {
+		return
+	}
+	if e := newCacheEntry(status, header, body, c.now(), cfg); e != nil {
+		e.base = base
+		e.key = varyKey(r, base, vary)
+		c.store(e, vary)
+	}
+}
+
+// newCacheEntry returns the entry of the response, or nil if it is not cacheable.
+func newCacheEntry(status int, header http.Header, body []byte, now time.Time, cfg *cacheConfig) *cacheEntry {
+	if !cacheableStatus(status) || header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	if _, ok := cc["no-store"]; ok {
+		return nil
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return nil
+	}
+	if _, ok := cc["private"]; ok {
+		return nil
+	}
+	maxAge, ok := cc.seconds("s-maxage")
+	if !ok {
+		maxAge, ok = cc.seconds("max-age")
+	}
+	if !ok {
+		maxAge = cfg.maxAge
+	}
+	if maxAge <= 0 {
+		return nil
+	}
+	swr, _ := cc.seconds("stale-while-revalidate")
+	return &cacheEntry{
+		status:     status,
+		header:     header,
+		body:       body,
+		stored:     now,
+		freshUntil: now.Add(maxAge),
+		staleUntil: now.Add(maxAge + swr),
+	}
+}
+
+// writeCached writes the cached response.
+func writeCached(w http.ResponseWriter, e *cacheEntry, state string, now time.Time) {
+	h := w.Header()
+	h.Set(HeaderCache, state)
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.stored).Seconds())))
+	writeRecorded(w, e.status, e.header, e.body)
+}
+
+// cacheKey returns the cache key of the request.
+func cacheKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.Path)
+	if q := r.URL.Query(); len(q) > 0 {
+		b.WriteByte('?')
+		b.WriteString(q.Encode()) // sorted by the parameter names
+	}
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(strings.ToLower(name))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// varyHeaders returns the sorted canonical names of the request headers named by the Vary
+// header of the response. It reports false for Vary: *, i.e. the response is not cacheable.
+func varyHeaders(header http.Header) ([]string, bool) {
+	var names []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), true
+}
+
+// varyKey returns the cache key of the request extending the base key with the values of
+// the request headers the response varies by.
+func varyKey(r *http.Request, base string, vary []string) string {
+	if len(vary) == 0 {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	b.WriteString("\nvary")
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// cacheableStatus reports whether the responses with the status code are cacheable by
+// default, see RFC 9110 section 15.1.
+func cacheableStatus(code int) bool {
+	switch code {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// cacheControl are the Cache-Control directives by their lower case names.
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control header value.
+func parseCacheControl(v string) cacheControl {
+	cc := make(cacheControl)
+	for _, directive := range strings.Split(v, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name == "" {
+			continue
+		}
+		cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return cc
+}
+
+// seconds returns the value of the directive in seconds as time.Duration.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// discardWriter is an http.ResponseWriter discarding the response, it is used to call the
+// handlers in the background.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) WriteHeader(int)             {}
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
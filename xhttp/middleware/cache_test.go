@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// countingHandler returns a handler responding with the number of its calls and the
+// supplied Cache-Control header.
+func countingHandler(calls *atomic.Int32, cacheControl string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		xhttp.OK(w, strconv.Itoa(int(n)))
+	})
+}
+
+func TestCache(t *testing.T) {
+	type request struct {
+		method string
+		target string
+		header http.Header
+	}
+	tests := []struct {
+		name         string
+		opts         []CacheOption
+		cacheControl string
+		first        request
+		second       request
+		wantCache    string
+		wantBody     string
+	}{
+		{
+			name:         "should serve cached response",
+			cacheControl: "public, max-age=60",
+			first:        request{method: http.MethodGet, target: "/locations"},
+			second:       request{method: http.MethodGet, target: "/locations"},
+			wantCache:    "HIT",
+			wantBody:     `"1"`,
+		},
+		{
+			name:         "should serve cached response of query in different order",
+			cacheControl: "s-maxage=60",
+			first:        request{method: http.MethodGet, target: "/locations?country=pl&level=2"},
+			second:       request{method: http.MethodGet, target: "/locations?level=2&country=pl"},
+			wantCache:    "HIT",
+			wantBody:     `"1"`,
+		},
+		{
+			name:         "should not serve cached response of different query",
+			cacheControl: "max-age=60",
+			first:        request{method: http.MethodGet, target: "/locations?country=pl"},
+			second:       request{method: http.MethodGet, target: "/locations?country=pt"},
+			wantCache:    "MISS",
+			wantBody:     `"2"`,
+		},
+		{
+			name:         "should not serve cached response of different vary header",
+			opts:         []CacheOption{WithVaryHeaders("Accept-Language")},
+			cacheControl: "max-age=60",
+			first:        request{method: http.MethodGet, target: "/locations", header: http.Header{"Accept-Language": {"pl"}}},
+			second:       request{method: http.MethodGet, target: "/locations", header: http.Header{"Accept-Language": {"en"}}},
+			wantCache:    "MISS",
+			wantBody:     `"2"`,
+		},
+		{
+			name:         "should not cache response with no-store",
+			cacheControl: "no-store",
+			first:        request{method: http.MethodGet, target: "/locations"},
+			second:       request{method: http.MethodGet, target: "/locations"},
+			wantCache:    "MISS",
+			wantBody:     `"2"`,
+		},
+		{
+			name:         "should not cache private response",
+			cacheControl: "private, max-age=60",
+			first:        request{method: http.MethodGet, target: "/locations"},
+			second:       request{method: http.MethodGet, target: "/locations"},
+			wantCache:    "MISS",
+			wantBody:     `"2"`,
+		},
+		{
+			name:      "should not cache response without max-age",
+			first:     request{method: http.MethodGet, target: "/locations"},
+			second:    request{method: http.MethodGet, target: "/locations"},
+			wantCache: "MISS",
+			wantBody:  `"2"`,
+		},
+		{
+			name:      "should cache response without max-age using default",
+			opts:      []CacheOption{WithDefaultMaxAge(time.Minute)},
+			first:     request{method: http.MethodGet, target: "/locations"},
+			second:    request{method: http.MethodGet, target: "/locations"},
+			wantCache: "HIT",
+			wantBody:  `"1"`,
+		},
+		{
+			name:         "should not cache request with authorization",
+			cacheControl: "max-age=60",
+			first:        request{method: http.MethodGet, target: "/locations", header: http.Header{"Authorization": {"Bearer token"}}},
+			second:       request{method: http.MethodGet, target: "/locations", header: http.Header{"Authorization": {"Bearer token"}}},
+			wantBody:     `"2"`,
+		},
+		{
+			name:     "should not cache request with cookie",
+			opts:     []CacheOption{WithDefaultMaxAge(time.Minute)},
+			first:    request{method: http.MethodGet, target: "/locations", header: http.Header{"Cookie": {"session=alice"}}},
+			second:   request{method: http.MethodGet, target: "/locations", header: http.Header{"Cookie": {"session=bob"}}},
+			wantBody: `"2"`,
+		},
+		{
+			name:         "should not cache post request",
+			cacheControl: "max-age=60",
+			first:        request{method: http.MethodPost, target: "/locations"},
+			second:       request{method: http.MethodPost, target: "/locations"},
+			wantBody:     `"2"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Cache(NewResponseCache(10), tt.opts...)(countingHandler(&calls, tt.cacheControl))
+			send := func(req request) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(req.method, req.target, nil)
+				for k, v := range req.header {
+					r.Header[k] = v
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				return w
+			}
+
+			send(tt.first)
+			w := send(tt.second)
+
+			if got := w.Header().Get(HeaderCache); got != tt.wantCache {
+				t.Errorf("Cache() = X-Cache header got %q, want %q", got, tt.wantCache)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Cache() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCache_Vary(t *testing.T) {
+	t.Run("should cache responses by headers named by vary", func(t *testing.T) {
+		var calls atomic.Int32
+		handler := Cache(NewResponseCache(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept")
+			xhttp.OK(w, strconv.Itoa(int(calls.Add(1)))+" "+r.Header.Get("Accept"))
+		}))
+		send := func(accept string) string {
+			r := httptest.NewRequest(http.MethodGet, "/locations", nil)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w.Header().Get(HeaderCache) + " " + w.Body.String()
+		}
+
+		got := []string{send("application/xml"), send("application/json"), send("application/xml"), send("application/json")}
+
+		want := []string{`MISS "1 application/xml"`, `MISS "2 application/json"`, `HIT "1 application/xml"`, `HIT "2 application/json"`}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Cache() = responses got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should not cache response with vary star", func(t *testing.T) {
+		var calls atomic.Int32
+		handler := Cache(NewResponseCache(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "*")
+			xhttp.OK(w, strconv.Itoa(int(calls.Add(1))))
+		}))
+		for i := 0; i < 2; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/locations", nil))
+		}
+
+		if got := calls.Load(); got != 2 {
+			t.Errorf("Cache() = handler calls got %d, want 2", got)
+		}
+	})
+}
+
+func TestCache_Compress(t *testing.T) {
+	var calls atomic.Int32
+	handler := Cache(NewResponseCache(10))(Compress(WithMinSize(0))(countingHandler(&calls, "max-age=60")))
+	send := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/locations", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	send("gzip")
+	w := send("")
+	if got := w.Header().Get(HeaderCache) + " " + w.Header().Get("Content-Encoding") + " " + w.Body.String(); got != `MISS  "2"` {
+		t.Errorf("Cache() = identity response got %q, want %q", got, `MISS  "2"`)
+	}
+	w = send("gzip")
+	if got := w.Header().Get(HeaderCache) + " " + w.Header().Get("Content-Encoding"); got != "HIT gzip" {
+		t.Errorf("Cache() = gzip response got %q, want %q", got, "HIT gzip")
+	}
+	w = send("")
+	if got := w.Header().Get(HeaderCache) + " " + w.Body.String(); got != `HIT "2"` {
+		t.Errorf("Cache() = identity response got %q, want %q", got, `HIT "2"`)
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	c := NewResponseCache(10)
+	c.now = clock.Now
+	var calls atomic.Int32
+	revalidated := make(chan struct{})
+	next := countingHandler(&calls, "max-age=60, stale-while-revalidate=30")
+	handler := Cache(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if calls.Load() == 2 {
+			close(revalidated)
+		}
+	}))
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/locations", nil))
+		return w
+	}
+
+	send()
+	clock.Advance(70 * time.Second)
+	w := send()
+	if got, want := w.Header().Get(HeaderCache)+" "+w.Body.String()+" "+w.Header().Get("Age"), `STALE "1" 70`; got != want {
+		t.Errorf("Cache() = stale response got %s, want %s", got, want)
+	}
+
+	// the revalidated response is stored right after the handler returns, the stale one is
+	// served until then without calling the handler again
+	<-revalidated
+	for w = send(); w.Header().Get(HeaderCache) == "STALE"; w = send() {
+		runtime.Gosched()
+	}
+	if got, want := w.Header().Get(HeaderCache)+" "+w.Body.String(), `HIT "2"`; got != want {
+		t.Errorf("Cache() = revalidated response got %s, want %s", got, want)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Cache() = handler calls got %d, want 2", got)
+	}
+
+	clock.Advance(100 * time.Second)
+	if got := send().Header().Get(HeaderCache); got != "MISS" {
+		t.Errorf("Cache() = expired response X-Cache header got %q, want %q", got, "MISS")
+	}
+}
+
+func TestResponseCache_Stats(t *testing.T) {
+	c := NewResponseCache(2)
+	var calls atomic.Int32
+	handler := Cache(c)(countingHandler(&calls, "max-age=60"))
+	for _, target := range []string{"/a", "/a", "/b", "/c", "/a"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	want := CacheStats{Hits: 1, Misses: 4, Evictions: 2, Entries: 2}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = got %+v, want %+v", got, want)
+	}
+
+	c.Purge()
+	if got := c.Stats().Entries; got != 0 {
+		t.Errorf("Stats() = entries after purge got %d, want 0", got)
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	got := parseCacheControl(`public, Max-Age=60, stale-while-revalidate="30"`)
+	want := cacheControl{"public": "", "max-age": "60", "stale-while-revalidate": "30"}
+	if len(got) != len(want) {
+		t.Fatalf("parseCacheControl() = got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseCacheControl() = got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOption configures the Compress middleware.
+type CompressOption func(*compressConfig)
+
+type compressConfig struct {
+	level    int
+	minSize  int
+	excluded []string
+}
+
+// WithCompressionLevel sets the compression level of the gzip and deflate encoders,
+// defaults to gzip.DefaultCompression. Compress panics if the level is not in the range
+// from gzip.HuffmanOnly to gzip.BestCompression.
+func WithCompressionLevel(level int) CompressOption {
+	return func(c *compressConfig) {
+		c.level = level
+	}
+}
+
+// WithMinSize sets the minimum size in bytes of the response body to be compressed,
+// defaults to 1024. Flushed (streamed) responses are compressed regardless of their size.
+func WithMinSize(n int) CompressOption {
+	return func(c *compressConfig) {
+		c.minSize = n
+	}
+}
+
+// WithExcludedContentTypes adds the content types that are not compressed. A type ending
+// with "/" excludes all its subtypes, e.g. "image/". By default, images (except SVG), audio,
+// video and common archive types are excluded.
+func WithExcludedContentTypes(types ...string) CompressOption {
+	return func(c *compressConfig) {
+		c.excluded = append(c.excluded, types...)
+	}
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/", "audio/", "video/", "font/woff", "font/woff2",
+	"application/gzip", "application/x-gzip", "application/zip", "application/x-bzip2",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/zstd",
+}
+
+// Compress returns middleware that compresses the response bodies using gzip or deflate
+// encoding negotiated from the request Accept-Encoding header.
+//
+// Responses smaller than the minimum size, responses of the excluded content types and
+// responses that already have the Content-Encoding header are written as is. The
+// Vary: Accept-Encoding header is added to all the responses. Strong ETags of the
+// compressed responses are weakened, e.g. "abc" becomes W/"abc".
+//
+// Flushing the response (e.g. by the streaming writers) flushes the compressed data
+// written so far to the client.
+func Compress(opts ...CompressOption) func(http.Handler) http.Handler {
+	cfg := &compressConfig{
+		level:    gzip.DefaultCompression,
+		minSize:  1024,
+		excluded: append([]string(nil), defaultExcludedContentTypes...),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if _, err := gzip.NewWriterLevel(io.Discard, cfg.level); err != nil {
+		panic(fmt.Sprintf("middleware: invalid compression level %d", cfg.level))
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, cfg.level)
+			return w
+		}},
+		"deflate": {New: func() any {
+			w, _ := flate.NewWriter(io.Discard, cfg.level)
+			return w
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, cfg: cfg, encoding: encoding, pool: pools[encoding]}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressor is implemented by both gzip.Writer and flate.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the beginning of the response body until it can decide whether
+// the response should be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *compressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	comp    compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	if code == http.StatusNotModified {
+		// the not modified response validates the compressed representation
+		weakenETag(w.Header())
+	}
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.cfg.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.comp != nil {
+		return w.comp.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface. Undecided responses get compressed regardless
+// of their size, since they are likely to be streamed.
+func (w *compressWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.comp != nil {
+		_ = w.comp.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// decide writes the header of the response, compressed if compress is true and the
+// response is eligible for compression, and the buffered body.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && h.Get("Content-Encoding") == "" && !w.excluded(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		weakenETag(h)
+		w.comp = w.pool.Get().(compressor)
+		w.comp.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.comp != nil {
+		_, err := w.comp.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close writes the remaining buffered body and finishes the compressed stream.
+func (w *compressWriter) close() {
+	if w.status == 0 {
+		return
+	}
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.comp != nil {
+		_ = w.comp.Close()
+		w.comp.Reset(io.Discard)
+		w.pool.Put(w.comp)
+		w.comp = nil
+	}
+}
+
+// weakenETag marks the strong ETag header as weak, the compressed body is not byte for byte
+// equal to the representation the strong tag was computed for.
+func weakenETag(h http.Header) {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+}
+
+func (w *compressWriter) excluded(contentType string) bool {
+	typ, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		typ = contentType
+	}
+	for _, ex := range w.cfg.excluded {
+		if typ == ex || strings.HasSuffix(ex, "/") && strings.HasPrefix(typ, ex) && typ != "image/svg+xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality according
+// to the Accept-Encoding header value, gzip is preferred on ties. It returns an empty
+// string if none of the supported encodings is acceptable.
+func negotiateEncoding(header string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		quality := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				quality = f
+			}
+		}
+		q[coding] = quality
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		quality, ok := q[coding]
+		if !ok {
+			quality, ok = q["*"]
+		}
+		if ok && quality > bestQ {
+			best, bestQ = coding, quality
+		}
+	}
+	return best
+}
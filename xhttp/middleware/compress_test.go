@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		r = zr
+	case "deflate":
+		r = flate.NewReader(body)
+	default:
+		r = body
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading body error = %v", err)
+	}
+	return string(b)
+}
+
+func TestCompress(t *testing.T) {
+	large := map[string]string{"value": strings.Repeat("listing ", 200)}
+	largeJSON := `{"value":"` + strings.Repeat("listing ", 200) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		wantEncoding   string
+		wantBody       string
+	}{
+		{
+			name:           "should compress large body with gzip",
+			acceptEncoding: "deflate, gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) { xhttp.OK(w, large) },
+			wantEncoding:   "gzip",
+			wantBody:       largeJSON,
+		},
+		{
+			name:           "should compress with preferred encoding",
+			acceptEncoding: "gzip;q=0.5, deflate",
+			handler:        func(w http.ResponseWriter, r *http.Request) { xhttp.OK(w, large) },
+			wantEncoding:   "deflate",
+			wantBody:       largeJSON,
+		},
+		{
+			name:           "should not compress if encoding is not acceptable",
+			acceptEncoding: "br, gzip;q=0",
+			handler:        func(w http.ResponseWriter, r *http.Request) { xhttp.OK(w, large) },
+			wantBody:       largeJSON,
+		},
+		{
+			name:           "should not compress small body",
+			acceptEncoding: "gzip",
+			handler:        func(w http.ResponseWriter, r *http.Request) { xhttp.OK(w, map[string]string{"value": "small"}) },
+			wantBody:       `{"value":"small"}`,
+		},
+		{
+			name:           "should not compress excluded content types",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte(strings.Repeat("a", 2048)))
+			},
+			wantBody: strings.Repeat("a", 2048),
+		},
+		{
+			name:           "should not compress already encoded body",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(strings.Repeat("a", 2048)))
+			},
+			wantEncoding: "br",
+			wantBody:     strings.Repeat("a", 2048),
+		},
+		{
+			name:           "should compress body written in chunks",
+			acceptEncoding: "*",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; i < 300; i++ {
+					w.Write([]byte("chunk"))
+				}
+			},
+			wantEncoding: "gzip",
+			wantBody:     strings.Repeat("chunk", 300),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/listings", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			Compress()(tt.handler).ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Compress() = encoding got %q, want %q", got, tt.wantEncoding)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Compress() = vary got %q, want %q", got, "Accept-Encoding")
+			}
+			if got := decompress(t, w.Header().Get("Content-Encoding"), w.Body); got != tt.wantBody {
+				t.Errorf("Compress() = body got %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCompress_NoContent(t *testing.T) {
+	r := httptest.NewRequest(http.MethodDelete, "/listings/1", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.WriteResponse(w, http.StatusNoContent, nil)
+	})).ServeHTTP(w, r)
+
+	if got := w.Code; got != http.StatusNoContent {
+		t.Errorf("Compress() = status got %d, want %d", got, http.StatusNoContent)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Compress() = expected encoding to be empty got %q", got)
+	}
+}
+
+func TestCompress_ETag(t *testing.T) {
+	large := map[string]string{"value": strings.Repeat("listing ", 200)}
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OK(w, large, xhttp.Conditional(r))
+	}))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		ifNoneMatch    bool
+		wantStatus     int
+		wantWeak       bool
+	}{
+		{
+			name:           "should weaken etag of compressed response",
+			acceptEncoding: "gzip",
+			wantStatus:     http.StatusOK,
+			wantWeak:       true,
+		},
+		{
+			name:       "should keep strong etag of uncompressed response",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "should reply not modified with weak etag",
+			acceptEncoding: "gzip",
+			ifNoneMatch:    true,
+			wantStatus:     http.StatusNotModified,
+			wantWeak:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings/1", nil))
+			etag := w.Header().Get("ETag")
+
+			r := httptest.NewRequest(http.MethodGet, "/listings/1", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			if tt.ifNoneMatch {
+				r.Header.Set("If-None-Match", "W/"+etag)
+			}
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Compress() = status got %d, want %d", got, tt.wantStatus)
+			}
+			want := etag
+			if tt.wantWeak {
+				want = "W/" + etag
+			}
+			if got := w.Header().Get("ETag"); got != want {
+				t.Errorf("Compress() = etag got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCompress_Streaming(t *testing.T) {
+	flushed := make(chan string)
+	server := httptest.NewServer(Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := make(chan int)
+		go func() {
+			defer close(items)
+			items <- 1
+			<-flushed
+			items <- 2
+		}()
+		_ = xhttp.StreamJSONLines(w, r, items, xhttp.FlushEvery(1))
+	})))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer res.Body.Close()
+
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Compress() = encoding got %q, want %q", got, "gzip")
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	// the first item is readable before the second one is produced
+	first := make([]byte, 2)
+	if _, err := io.ReadFull(zr, first); err != nil || string(first) != "1\n" {
+		t.Fatalf("Compress() = first item got %q (%v), want %q", first, err, "1\n")
+	}
+	close(flushed)
+	rest, _ := io.ReadAll(zr)
+	if string(rest) != "2\n" {
+		t.Errorf("Compress() = rest got %q, want %q", rest, "2\n")
+	}
+}
+
+func TestCompress_InvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Compress() = expected panic for invalid compression level")
+		}
+	}()
+	Compress(WithCompressionLevel(42))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "identity", want: ""},
+		{header: "gzip, deflate, br", want: "gzip"},
+		{header: "deflate", want: "deflate"},
+		{header: "gzip;q=0.1, deflate;q=0.2", want: "deflate"},
+		{header: "*;q=0.5, gzip;q=0", want: "deflate"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = got %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOption configures the CORS middleware.
+type CORSOption func(*corsConfig)
+
+type corsConfig struct {
+	anyOrigin   bool
+	origins     map[string]struct{}
+	wildcards   [][2]string // scheme and domain suffix pairs, e.g. {"https://", ".example.com"}
+	methods     []string
+	anyHeader   bool
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+// WithAllowedOrigins sets the origins allowed to make cross-origin requests. An origin is
+// either exact, e.g. "https://www.example.com", a wildcard subdomain, e.g.
+// "https://*.example.com", or "*" allowing any origin. By default, no origin is allowed.
+// Any origin cannot be combined with WithAllowCredentials.
+func WithAllowedOrigins(origins ...string) CORSOption {
+	return func(c *corsConfig) {
+		for _, origin := range origins {
+			origin = strings.ToLower(origin)
+			switch {
+			case origin == "*":
+				c.anyOrigin = true
+			case strings.Contains(origin, "://*."):
+				scheme, domain, _ := strings.Cut(origin, "*")
+				c.wildcards = append(c.wildcards, [2]string{scheme, domain})
+			default:
+				c.origins[origin] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithAllowedMethods sets the methods allowed in cross-origin requests, defaults to GET,
+// HEAD and POST.
+func WithAllowedMethods(methods ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.methods = methods
+	}
+}
+
+// WithAllowedHeaders sets the request headers allowed in cross-origin requests, "*" allows
+// any header. Defaults to Accept, Accept-Language, Content-Language and Content-Type.
+func WithAllowedHeaders(headers ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.headers = c.headers[:0]
+		for _, h := range headers {
+			if h == "*" {
+				c.anyHeader = true
+				continue
+			}
+			c.headers = append(c.headers, h)
+		}
+	}
+}
+
+// WithExposedHeaders sets the response headers exposed to the cross-origin clients.
+func WithExposedHeaders(headers ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.exposed = headers
+	}
+}
+
+// WithAllowCredentials allows the cross-origin requests to include credentials such as
+// cookies or the Authorization header. The origins must be listed explicitly, since any site
+// could make the credentialed requests if any origin was allowed.
+func WithAllowCredentials() CORSOption {
+	return func(c *corsConfig) {
+		c.credentials = true
+	}
+}
+
+// WithMaxAge sets how long the clients may cache the preflight responses.
+func WithMaxAge(d time.Duration) CORSOption {
+	return func(c *corsConfig) {
+		c.maxAge = d
+	}
+}
+
+// CORS returns middleware that handles Cross-Origin Resource Sharing.
+//
+// Preflight requests, i.e. OPTIONS requests with the Origin and Access-Control-Request-Method
+// headers, are replied with an HTTP 204 StatusNoContent and never reach the next handler.
+// The Access-Control-Allow-* headers are only set for the allowed origins, methods and
+// headers, so the client rejects anything else. Other requests from the allowed origins get
+// the Access-Control-Allow-Origin header and are passed to the next handler.
+//
+// The Vary: Origin header is added to all the responses, since they depend on the origin.
+//
+// It panics if any origin is allowed along with the credentials, see WithAllowCredentials.
+func CORS(opts ...CORSOption) func(http.Handler) http.Handler {
+	cfg := &corsConfig{
+		origins: make(map[string]struct{}),
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		headers: []string{"Accept", "Accept-Language", "Content-Language", "Content-Type"},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.anyOrigin && cfg.credentials {
+		panic(`middleware: CORS cannot allow credentials for any origin "*", list the allowed origins instead`)
+	}
+	allowMethods := strings.Join(cfg.methods, ", ")
+	allowHeaders := strings.Join(cfg.headers, ", ")
+	exposeHeaders := strings.Join(cfg.exposed, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			origin := r.Header.Get("Origin")
+			if r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Origin")
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				if cfg.allowedOrigin(origin) &&
+					slices.Contains(cfg.methods, r.Header.Get("Access-Control-Request-Method")) &&
+					cfg.allowedHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+					cfg.setOrigin(h, origin)
+					h.Set("Access-Control-Allow-Methods", allowMethods)
+					if requested := r.Header.Get("Access-Control-Request-Headers"); cfg.anyHeader && requested != "" {
+						h.Set("Access-Control-Allow-Headers", requested)
+					} else if allowHeaders != "" {
+						h.Set("Access-Control-Allow-Headers", allowHeaders)
+					}
+					if cfg.maxAge > 0 {
+						h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.maxAge.Seconds())))
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Add("Vary", "Origin")
+			if origin != "" && cfg.allowedOrigin(origin) {
+				cfg.setOrigin(h, origin)
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setOrigin sets the Access-Control-Allow-Origin header, and the credentials header if
+// the credentials are allowed.
+func (c *corsConfig) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *corsConfig) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := c.origins[origin]; ok {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders reports whether all the headers of the comma-separated list are allowed.
+func (c *corsConfig) allowedHeaders(list string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, h := range strings.Split(list, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if !slices.ContainsFunc(c.headers, func(a string) bool { return strings.EqualFold(a, h) }) {
+			return false
+		}
+	}
+	return true
+}
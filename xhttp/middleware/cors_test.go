@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	cors := CORS(
+		WithAllowedOrigins("https://www.example.com", "https://*.example.org"),
+		WithAllowedMethods(http.MethodGet, http.MethodPut),
+		WithAllowedHeaders("Content-Type", "x-request-id"),
+		WithExposedHeaders("X-Request-ID", "ETag"),
+		WithAllowCredentials(),
+		WithMaxAge(10*time.Minute),
+	)
+
+	tests := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		wantStatus  int
+		wantNext    bool
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "should handle allowed preflight request",
+			method:     http.MethodOptions,
+			headers:    map[string]string{"Origin": "https://www.example.com", "Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "content-type, X-Request-ID"},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://www.example.com",
+				"Access-Control-Allow-Methods":     "GET, PUT",
+				"Access-Control-Allow-Headers":     "Content-Type, x-request-id",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:       "should reject preflight request with disallowed method",
+			method:     http.MethodOptions,
+			headers:    map[string]string{"Origin": "https://www.example.com", "Access-Control-Request-Method": "DELETE"},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:       "should reject preflight request with disallowed header",
+			method:     http.MethodOptions,
+			headers:    map[string]string{"Origin": "https://www.example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "Authorization"},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:       "should handle request from wildcard subdomain",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://api.eu.example.org"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://api.eu.example.org",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-ID, ETag",
+			},
+		},
+		{
+			name:       "should not allow parent domain of wildcard subdomain",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://example.org"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:       "should not allow unknown origin",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://evil.com"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			name:       "should pass options request without origin",
+			method:     http.MethodOptions,
+			headers:    map[string]string{"Access-Control-Request-Method": "GET"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			r := httptest.NewRequest(tt.method, "/listings", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })).ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("CORS() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if called != tt.wantNext {
+				t.Errorf("CORS() = next handler called got %v, want %v", called, tt.wantNext)
+			}
+			for k, v := range tt.wantHeaders {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("CORS() = header %s got %q, want %q", k, got, v)
+				}
+			}
+			if got := w.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+				t.Errorf("CORS() = vary got %q, want Origin first", got)
+			}
+		})
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []CORSOption
+		wantHeaders http.Header
+	}{
+		{
+			name: "should allow any origin",
+			opts: []CORSOption{WithAllowedOrigins("*"), WithAllowedHeaders("*")},
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":  {"*"},
+				"Access-Control-Allow-Methods": {"GET, HEAD, POST"},
+				"Access-Control-Allow-Headers": {"Authorization, X-Custom"},
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/listings", nil)
+			r.Header.Set("Origin", "https://www.example.com")
+			r.Header.Set("Access-Control-Request-Method", "POST")
+			r.Header.Set("Access-Control-Request-Headers", "Authorization, X-Custom")
+			w := httptest.NewRecorder()
+			CORS(tt.opts...)(http.NotFoundHandler()).ServeHTTP(w, r)
+
+			if got := w.Header(); !reflect.DeepEqual(got, tt.wantHeaders) {
+				t.Errorf("CORS() = headers got %v, want %v", got, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestCORS_AnyOriginWithCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("CORS() = expected panic for any origin with credentials")
+		}
+	}()
+	CORS(WithAllowedOrigins("https://www.example.com", "*"), WithAllowCredentials())
+}
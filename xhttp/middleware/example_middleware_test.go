@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func ExampleChain() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/listings", func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OK(w, map[string]string{"requestId": RequestIDFromContext(r.Context())})
+	})
+
+	// request ID is resolved first, so that both the access log and the recovered panics carry it
+	handler := Chain(
+		RequestID(),
+		AccessLog(logger),
+		Recover(WithRecoverLogger(logger)),
+	)(mux)
+
+	_ = http.ListenAndServe(":8080", handler)
+}
+
+func ExampleCompress() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// large JSON responses get compressed with gzip or deflate negotiated from Accept-Encoding
+		xhttp.OK(w, map[string]string{"value": "listing"})
+	})
+
+	http.Handle("/listings", Compress(WithMinSize(2048), WithExcludedContentTypes("application/pdf"))(handler))
+}
+
+func ExampleRateLimit() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OK(w, map[string]string{"value": "listing"})
+	})
+
+	// 100 requests per minute per API key with bursts of up to 20 requests
+	limiter := NewLimiter(100, time.Minute, 20)
+	http.Handle("/listings", RateLimit(limiter, WithKeyFunc(KeyByHeader("X-API-Key")))(handler))
+}
+
+func ExampleCORS() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OK(w, map[string]string{"value": "listing"})
+	})
+
+	cors := CORS(
+		WithAllowedOrigins("https://www.example.com", "https://*.example.com"),
+		WithAllowedMethods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete),
+		WithAllowedHeaders("Content-Type", "Authorization"),
+		WithExposedHeaders(HeaderRequestID),
+		WithAllowCredentials(),
+		WithMaxAge(time.Hour),
+	)
+	http.Handle("/listings", cors(handler))
+}
+
+func ExampleTimeout() {
+	search := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the queries should use r.Context() to stop as soon as the deadline passes
+		xhttp.OK(w, map[string]string{"value": "listing"})
+	})
+	export := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mux := http.NewServeMux()
+	mux.Handle("/search", Timeout(2*time.Second)(search))
+	mux.Handle("/export", Timeout(30*time.Second, WithTimeoutStatus(http.StatusGatewayTimeout))(export))
+}
+
+func ExampleBodyLimit() {
+	type listing struct {
+		Title string `json:"title"`
+	}
+	create := xhttp.Handle(func(ctx context.Context, req listing) (listing, error) {
+		return req, nil
+	})
+
+	http.Handle("/listings", BodyLimit(1<<20)(create))
+}
+
+func ExampleIdempotency() {
+	type listing struct {
+		Title string `json:"title"`
+	}
+	create := xhttp.Handle(func(ctx context.Context, req listing) (listing, error) {
+		return req, nil
+	}, xhttp.WithStatus(http.StatusCreated))
+
+	store := NewMemoryIdempotencyStore(24 * time.Hour)
+	idempotency := Idempotency(store, WithIdempotencyScope(KeyByHeader("X-API-Key")))
+	http.Handle("/listings", idempotency(create))
+}
+
+func ExampleCache() {
+	locations := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300, stale-while-revalidate=60")
+		xhttp.OK(w, []string{"Warszawa", "Kraków"})
+	})
+
+	cache := NewResponseCache(1000)
+	http.Handle("/locations", Cache(cache, WithVaryHeaders("Accept-Language"))(locations))
+	http.Handle("/debug/cache", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OK(w, cache.Stats())
+	}))
+}
@@ -0,0 +1,288 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+const (
+	// HeaderIdempotencyKey is the header carrying the idempotency key of the request.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set to "true" on the responses replayed from the store.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength is the maximum length of the accepted idempotency keys.
+const maxIdempotencyKeyLength = 255
+
+// IdempotentResponse is the response stored for an idempotency key.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyRecord is the state of an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request the key has been first used with.
+	Fingerprint string
+	// Response is the stored response, nil while the first request is in flight.
+	Response *IdempotentResponse
+}
+
+// IdempotencyStore stores the state of the idempotency keys. Implementations must be safe
+// for concurrent use, and Reserve must be atomic, e.g. SET NX in Redis, for the concurrent
+// duplicates to be detected across the instances of the service.
+type IdempotencyStore interface {
+	// Reserve stores rec for the key unless the key is already present, in which case it
+	// returns the present record. It returns nil if the key has been reserved.
+	Reserve(ctx context.Context, key string, rec IdempotencyRecord) (*IdempotencyRecord, error)
+	// Save replaces the record of the reserved key, i.e. stores the response.
+	Save(ctx context.Context, key string, rec IdempotencyRecord) error
+	// Release removes the key, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. Keys expire after the
+// configured TTL, the expired keys are evicted lazily.
+//
+// It is suitable for a single instance of a service or tests, use a shared store otherwise.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	records   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+// NewMemoryIdempotencyStore returns a new MemoryIdempotencyStore keeping the keys for ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		records: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Reserve implements IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key string, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		present := e.rec
+		return &present, nil
+	}
+	s.records[key] = &idempotencyEntry{rec: rec, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// Save implements IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = &idempotencyEntry{rec: rec, expires: s.now().Add(s.ttl)}
+	return nil
+}
+
+// Release implements IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Len returns the number of the stored keys, including the expired ones not evicted yet.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// sweep evicts the expired keys, at most once per TTL.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// IdempotencyOption configures the Idempotency middleware.
+type IdempotencyOption func(*idempotencyConfig)
+
+type idempotencyConfig struct {
+	methods     []string
+	scope       KeyFunc
+	maxBodySize int64
+}
+
+// WithIdempotentMethods sets the methods the idempotency keys are honoured for, defaults to
+// POST and PATCH.
+func WithIdempotentMethods(methods ...string) IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.methods = methods
+	}
+}
+
+// WithIdempotencyScope sets the function that identifies the clients, so the keys of
+// different clients do not collide, e.g. KeyByHeader("X-API-Key"), defaults to
+// KeyByAuthorization. Use it if the clients are authenticated otherwise, e.g. by a session
+// cookie.
+func WithIdempotencyScope(scope KeyFunc) IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.scope = scope
+	}
+}
+
+// WithIdempotencyMaxBodySize sets the maximum size of the request bodies read to compute
+// the fingerprints, defaults to 1 MiB. Larger requests are replied with an HTTP 413
+// StatusRequestEntityTooLarge problem details document.
+func WithIdempotencyMaxBodySize(n int64) IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// Idempotency returns middleware that makes the retries of the requests carrying the
+// Idempotency-Key header safe, e.g. creating a listing twice after a timeout on a mobile
+// network.
+//
+// The first request with a key is passed to the next handler and its response is saved in
+// the store along with the fingerprint of the request method, URI and body. Retries with the
+// same key and fingerprint are replied with the saved response and the Idempotent-Replayed
+// header, without calling the handler. Reusing the key for a different request is replied with
+// an HTTP 422 StatusUnprocessableEntity, and retries while the first request is still in
+// flight with an HTTP 409 StatusConflict problem details document.
+//
+// The keys are scoped to the clients, see WithIdempotencyScope, so a client cannot replay
+// the response of another one. The keys of unidentified clients, i.e. with an empty scope,
+// share a single scope, they can only be replayed to the requests with the same fingerprint.
+//
+// Server error responses are not saved, and neither are the responses of the panicking
+// handlers, so such requests can be retried with the same key.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) func(http.Handler) http.Handler {
+	cfg := &idempotencyConfig{
+		methods:     []string{http.MethodPost, http.MethodPatch},
+		scope:       KeyByAuthorization,
+		maxBodySize: 1 << 20,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if key == "" || !slices.Contains(cfg.methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				xhttp.WriteError(w, r, xhttp.ErrBadRequest("idempotency key is too long"))
+				return
+			}
+			// the keys of the unidentified clients share the empty scope
+			key = cfg.scope(r) + ":" + key
+
+			fingerprint, err := fingerprintRequest(r, cfg.maxBodySize)
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if !errors.As(err, &mbe) {
+					err = xhttp.ErrBadRequest("invalid body").Wrap(err)
+				}
+				xhttp.WriteError(w, r, err)
+				return
+			}
+
+			ctx := r.Context()
+			present, err := store.Reserve(ctx, key, IdempotencyRecord{Fingerprint: fingerprint})
+			if err != nil {
+				xhttp.WriteError(w, r, xhttp.ErrInternal(err))
+				return
+			}
+			if present != nil {
+				switch {
+				case present.Fingerprint != fingerprint:
+					xhttp.WriteError(w, r, xhttp.ErrUnprocessableEntity("idempotency key is already used for a different request"))
+				case present.Response == nil:
+					xhttp.WriteError(w, r, xhttp.ErrConflict("request with the same idempotency key is in progress"))
+				default:
+					replay(w, present.Response)
+				}
+				return
+			}
+
+			// the key must be saved or released even if the client has gone away
+			ctx = context.WithoutCancel(ctx)
+			rw := newRecordingWriter(w)
+			saved := false
+			defer func() {
+				if !saved {
+					_ = store.Release(ctx, key)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+
+			status, header, body := rw.result()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			res := &IdempotentResponse{Status: status, Header: header, Body: body}
+			saved = store.Save(ctx, key, IdempotencyRecord{Fingerprint: fingerprint, Response: res}) == nil
+		})
+	}
+}
+
+// fingerprintRequest returns the hash of the request method, URI and body. The body is
+// restored, so it can be read again by the next handler. Bodies larger than maxBodySize
+// result in *http.MaxBytesError.
+func fingerprintRequest(r *http.Request, maxBodySize int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		_ = r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > maxBodySize {
+			return "", &http.MaxBytesError{Limit: maxBodySize}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes the stored response.
+func replay(w http.ResponseWriter, res *IdempotentResponse) {
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	writeRecorded(w, res.Status, res.Header, res.Body)
+}
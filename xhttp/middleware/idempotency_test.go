@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		method string
+		key    string
+		body   string
+	}
+	tests := []struct {
+		name         string
+		first        request
+		retry        request
+		wantStatus   int
+		wantBody     string
+		wantReplayed string
+		wantCalls    int32
+	}{
+		{
+			name:         "should replay response of retry with same key and payload",
+			first:        request{method: http.MethodPost, key: "k1", body: `{"title":"flat"}`},
+			retry:        request{method: http.MethodPost, key: "k1", body: `{"title":"flat"}`},
+			wantStatus:   http.StatusCreated,
+			wantBody:     `{"id":"1"}`,
+			wantReplayed: "true",
+			wantCalls:    1,
+		},
+		{
+			name:       "should reject retry with same key and different payload",
+			first:      request{method: http.MethodPost, key: "k1", body: `{"title":"flat"}`},
+			retry:      request{method: http.MethodPost, key: "k1", body: `{"title":"house"}`},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"title":"Unprocessable Entity","status":422,"detail":"idempotency key is already used for a different request","instance":"/listings"}`,
+			wantCalls:  1,
+		},
+		{
+			name:       "should handle requests with different keys",
+			first:      request{method: http.MethodPost, key: "k1", body: `{"title":"flat"}`},
+			retry:      request{method: http.MethodPost, key: "k2", body: `{"title":"flat"}`},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"2"}`,
+			wantCalls:  2,
+		},
+		{
+			name:       "should handle requests without key",
+			first:      request{method: http.MethodPost, body: `{"title":"flat"}`},
+			retry:      request{method: http.MethodPost, body: `{"title":"flat"}`},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"2"}`,
+			wantCalls:  2,
+		},
+		{
+			name:       "should ignore key of not idempotent method",
+			first:      request{method: http.MethodPut, key: "k1", body: `{"title":"flat"}`},
+			retry:      request{method: http.MethodPut, key: "k1", body: `{"title":"flat"}`},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"2"}`,
+			wantCalls:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				xhttp.Created(w, map[string]string{"id": strconv.Itoa(int(n))})
+			}))
+			send := func(req request) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(req.method, "/listings", strings.NewReader(req.body))
+				r.Header.Set("Authorization", "Bearer alice")
+				if req.key != "" {
+					r.Header.Set(HeaderIdempotencyKey, req.key)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				return w
+			}
+
+			send(tt.first)
+			w := send(tt.retry)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Idempotency() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Idempotency() = body got %s, want %s", got, tt.wantBody)
+			}
+			if got := w.Header().Get(HeaderIdempotentReplayed); got != tt.wantReplayed {
+				t.Errorf("Idempotency() = replayed header got %q, want %q", got, tt.wantReplayed)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("Idempotency() = handler calls got %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		xhttp.Created(w, map[string]string{"id": "1"})
+	}))
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+		r.Header.Set(HeaderIdempotencyKey, "k1")
+		r.Header.Set("Authorization", "Bearer alice")
+		return r
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}()
+	<-started
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest())
+	close(release)
+	<-done
+
+	if got := w.Code; got != http.StatusConflict {
+		t.Errorf("Idempotency() = status got %d, want %d", got, http.StatusConflict)
+	}
+	want := `{"title":"Conflict","status":409,"detail":"request with the same idempotency key is in progress","instance":"/listings"}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("Idempotency() = body got %s, want %s", got, want)
+	}
+}
+
+func TestIdempotency_Release(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "should release key of server error response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				xhttp.WriteError(w, r, xhttp.ErrServiceUnavailable("database is down"))
+			},
+		},
+		{
+			name: "should release key of panicking handler",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryIdempotencyStore(time.Hour)
+			handler := Recover()(Idempotency(store)(tt.handler))
+			r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+			r.Header.Set(HeaderIdempotencyKey, "k1")
+			r.Header.Set("Authorization", "Bearer alice")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := store.Len(); got != 0 {
+				t.Errorf("Idempotency() = stored keys got %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestIdempotency_Headers(t *testing.T) {
+	handler := RequestID()(Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/listings/1")
+		xhttp.Created(w, map[string]string{"id": "1"})
+	})))
+	send := func(requestID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+		r.Header.Set(HeaderIdempotencyKey, "k1")
+		r.Header.Set("Authorization", "Bearer alice")
+		r.Header.Set(HeaderRequestID, requestID)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	send("first")
+	w := send("retry")
+
+	if got := w.Header().Get("Location"); got != "/listings/1" {
+		t.Errorf("Idempotency() = location header got %q, want %q", got, "/listings/1")
+	}
+	if got := w.Header().Get(HeaderRequestID); got != "retry" {
+		t.Errorf("Idempotency() = request ID header got %q, want %q", got, "retry")
+	}
+}
+
+func TestIdempotency_Scope(t *testing.T) {
+	var calls atomic.Int32
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour), WithIdempotencyScope(KeyByHeader("X-API-Key")))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		xhttp.Created(w, nil)
+	}))
+	for _, client := range []string{"a", "b", "a"} {
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+		r.Header.Set(HeaderIdempotencyKey, "k1")
+		r.Header.Set("X-API-Key", client)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Idempotency() = handler calls got %d, want 2", got)
+	}
+}
+
+func TestIdempotency_Clients(t *testing.T) {
+	var calls atomic.Int32
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		xhttp.Created(w, map[string]string{"owner": r.Header.Get("Authorization")})
+	}))
+	send := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+		r.Header.Set(HeaderIdempotencyKey, "k1")
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("should not replay response of another client", func(t *testing.T) {
+		send("Bearer alice")
+		w := send("Bearer bob")
+
+		if got := w.Header().Get(HeaderIdempotentReplayed); got != "" {
+			t.Errorf("Idempotency() = replayed header got %q, want empty", got)
+		}
+		if got, want := w.Body.String(), `{"owner":"Bearer bob"}`; got != want {
+			t.Errorf("Idempotency() = body got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should replay response of unidentified client", func(t *testing.T) {
+		calls.Store(0)
+		send("")
+		w := send("")
+
+		if got := w.Header().Get(HeaderIdempotentReplayed); got != "true" {
+			t.Errorf("Idempotency() = replayed header got %q, want %q", got, "true")
+		}
+		if got := w.Code; got != http.StatusCreated {
+			t.Errorf("Idempotency() = status got %d, want %d", got, http.StatusCreated)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("Idempotency() = handler calls got %d, want 1", got)
+		}
+	})
+}
+
+func TestIdempotency_MaxBodySize(t *testing.T) {
+	var calls atomic.Int32
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour), WithIdempotencyMaxBodySize(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		xhttp.Created(w, nil)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"flat"}`))
+	r.Header.Set(HeaderIdempotencyKey, "k1")
+	r.Header.Set("Authorization", "Bearer alice")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Code; got != http.StatusRequestEntityTooLarge {
+		t.Errorf("Idempotency() = status got %d, want %d", got, http.StatusRequestEntityTooLarge)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Errorf("Idempotency() = handler calls got %d, want 0", got)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.now = clock.Now
+	ctx := context.Background()
+
+	if present, _ := store.Reserve(ctx, "k1", IdempotencyRecord{Fingerprint: "a"}); present != nil {
+		t.Errorf("Reserve() = got %v, want nil", present)
+	}
+	present, _ := store.Reserve(ctx, "k1", IdempotencyRecord{Fingerprint: "b"})
+	if present == nil || present.Fingerprint != "a" {
+		t.Errorf("Reserve() = got %v, want record with fingerprint a", present)
+	}
+
+	clock.Advance(time.Minute)
+	if present, _ := store.Reserve(ctx, "k2", IdempotencyRecord{Fingerprint: "c"}); present != nil {
+		t.Errorf("Reserve() = got %v, want nil", present)
+	}
+	if got := store.Len(); got != 1 {
+		t.Errorf("Len() = got %d, want 1", got)
+	}
+}
@@ -0,0 +1,154 @@
+// Package middleware provides composable http.Handler middleware built on top of the xhttp package.
+
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// Chain composes the supplied middleware into a single one. The first middleware is the
+// outermost one, i.e. Chain(a, b)(h) is equivalent to a(b(h)).
+func Chain(middleware ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		return next
+	}
+}
+
+// responseWriter records the status code and the number of bytes written to the wrapped
+// http.ResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher interface if the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Status returns the status code of the response, or HTTP 200 StatusOK if the handler has
+// not written the header explicitly.
+func (w *responseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// written reports whether the response header has already been written.
+func (w *responseWriter) written() bool {
+	return w.status != 0
+}
+
+// wrap returns w as *responseWriter, wrapping it if it is not one already.
+func wrap(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w}
+}
+
+// recordingWriter records the response written to the wrapped http.ResponseWriter, so it
+// can be replayed later, see writeRecorded.
+//
+// Only the headers set by the next handler are recorded, the headers set by the outer
+// middleware, e.g. the request ID, are left out, so they are not replayed.
+type recordingWriter struct {
+	http.ResponseWriter
+	inherited http.Header
+	status    int
+	header    http.Header
+	body      bytes.Buffer
+}
+
+// newRecordingWriter returns a new recordingWriter wrapping w. The headers already set on w
+// are considered to be set by the outer middleware.
+func newRecordingWriter(w http.ResponseWriter) *recordingWriter {
+	return &recordingWriter{ResponseWriter: w, inherited: w.Header().Clone()}
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		w.header = w.handlerHeader()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface if the underlying writer supports it.
+func (w *recordingWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by http.ResponseController.
+func (w *recordingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// result returns the recorded status code, headers and body.
+func (w *recordingWriter) result() (status int, header http.Header, body []byte) {
+	if w.status == 0 {
+		return http.StatusOK, w.handlerHeader(), nil
+	}
+	return w.status, w.header, bytes.Clone(w.body.Bytes())
+}
+
+// handlerHeader returns the headers of the response that have been set by the next handler.
+func (w *recordingWriter) handlerHeader() http.Header {
+	h := make(http.Header)
+	for k, v := range w.ResponseWriter.Header() {
+		if !slices.Equal(v, w.inherited[k]) {
+			h[k] = slices.Clone(v)
+		}
+	}
+	return h
+}
+
+// writeRecorded writes the response recorded by recordingWriter to w.
+func writeRecorded(w http.ResponseWriter, status int, header http.Header, body []byte) {
+	h := w.Header()
+	for k, v := range header {
+		h[k] = slices.Clone(v)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(mw("a"), mw("b"), mw("c"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"a", "b", "c", "handler"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Chain() = calls got %v, want %v", calls, want)
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("should record status and bytes", func(t *testing.T) {
+		rw := wrap(httptest.NewRecorder())
+		rw.WriteHeader(http.StatusCreated)
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("abc"))
+		rw.Write([]byte("de"))
+
+		if got := rw.Status(); got != http.StatusCreated {
+			t.Errorf("Status() = got %d, want %d", got, http.StatusCreated)
+		}
+		if got := rw.bytes; got != 5 {
+			t.Errorf("bytes = got %d, want %d", got, 5)
+		}
+	})
+
+	t.Run("should default status to OK", func(t *testing.T) {
+		rw := wrap(httptest.NewRecorder())
+		if rw.written() {
+			t.Errorf("written() = got true, want false")
+		}
+		rw.Write([]byte("abc"))
+
+		if got := rw.Status(); got != http.StatusOK {
+			t.Errorf("Status() = got %d, want %d", got, http.StatusOK)
+		}
+	})
+
+	t.Run("should support flushing and unwrapping", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := wrap(rec)
+		if err := http.NewResponseController(rw).Flush(); err != nil {
+			t.Errorf("Flush() error = %v", err)
+		}
+		if !rec.Flushed {
+			t.Errorf("Flush() = expected underlying writer to be flushed")
+		}
+		if wrap(rw) != rw {
+			t.Errorf("wrap() = expected writer not to be wrapped twice")
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// KeyFunc returns the key identifying the client of the request r, e.g. the IP address or
+// the API key. Requests with an empty key are not rate limited.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP returns the IP address of the client from the request RemoteAddr. It does not
+// trust any forwarding headers, use KeyByHeader behind a trusted proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader returns a KeyFunc that uses the value of the request header name as the key,
+// e.g. "X-API-Key".
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByAuthorization returns the hex encoded SHA-256 hash of the request Authorization
+// header as the key, or an empty string if the header is not present. The credentials
+// themselves are not used as the key, so they do not end up in the stores.
+func KeyByAuthorization(r *http.Request) string {
+	v := r.Header.Get("Authorization")
+	if v == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
+
+// Decision is the result of the rate limit check of a single request.
+type Decision struct {
+	// Allowed reports whether the request is allowed.
+	Allowed bool
+	// Limit is the maximum number of requests in the burst.
+	Limit int
+	// Remaining is the number of requests that are allowed right away.
+	Remaining int
+	// RetryAfter is the time after which the next request will be allowed, zero if allowed right away.
+	RetryAfter time.Duration
+	// Reset is the time after which the limit is fully restored.
+	Reset time.Duration
+}
+
+// Limiter is an in-process token bucket rate limiter keyed by the client key.
+//
+// Each key gets a bucket of burst tokens refilled at the configured rate, every request
+// takes one token. Buckets that have been idle long enough to be full again are evicted,
+// so the memory is bounded by the number of the recently active clients.
+//
+// Limiter is safe for concurrent use.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a new Limiter that allows limit requests per the supplied period with
+// bursts of up to burst requests. A non-positive burst defaults to limit.
+//
+// It panics if limit or per is not positive.
+func NewLimiter(limit int, per time.Duration, burst int) *Limiter {
+	if limit <= 0 || per <= 0 {
+		panic(fmt.Sprintf("middleware: rate limit %d per %s must be positive", limit, per))
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+	return &Limiter{
+		rate:    float64(limit) / per.Seconds(),
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of the key and returns the Decision.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	d := Decision{Limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.duration(l.burst - b.tokens)
+	return d
+}
+
+// Len returns the number of the tracked buckets.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep evicts the buckets that would be full by now, at most once per the time it takes
+// to refill a bucket.
+func (l *Limiter) sweep(now time.Time) {
+	fill := l.duration(l.burst)
+	if now.Sub(l.lastSweep) < fill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration returns the time it takes to refill the supplied number of tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// RateLimitOption configures the RateLimit middleware.
+type RateLimitOption func(*rateLimitConfig)
+
+type rateLimitConfig struct {
+	key KeyFunc
+}
+
+// WithKeyFunc sets the function that identifies the clients, defaults to KeyByIP.
+func WithKeyFunc(key KeyFunc) RateLimitOption {
+	return func(c *rateLimitConfig) {
+		c.key = key
+	}
+}
+
+// RateLimit returns middleware that throttles the requests of each client using the
+// supplied Limiter.
+//
+// Every response gets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers. Requests exceeding the limit are replied with an HTTP 429 StatusTooManyRequests
+// problem details document and the Retry-After header.
+func RateLimit(limiter *Limiter, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	cfg := &rateLimitConfig{
+		key: KeyByIP,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := cfg.key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			d := limiter.Allow(key)
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(d.Reset)))
+			if !d.Allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(d.RetryAfter)))
+				xhttp.WriteError(w, r, xhttp.ErrTooManyRequests("rate limit exceeded"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds returns d rounded up to the whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time              { return c.now }
+func (c *fakeClock) Advance(d time.Duration)     { c.now = c.now.Add(d) }
+func newFakeClock() *fakeClock                   { return &fakeClock{now: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)} }
+func (c *fakeClock) limiter(l *Limiter) *Limiter { l.now = c.Now; return l }
+
+func TestLimiter_Allow(t *testing.T) {
+	clock := newFakeClock()
+	l := clock.limiter(NewLimiter(2, time.Second, 3))
+
+	var got []Decision
+	for i := 0; i < 4; i++ {
+		got = append(got, l.Allow("client"))
+	}
+	want := []Decision{
+		{Allowed: true, Limit: 3, Remaining: 2, Reset: 500 * time.Millisecond},
+		{Allowed: true, Limit: 3, Remaining: 1, Reset: time.Second},
+		{Allowed: true, Limit: 3, Remaining: 0, Reset: 1500 * time.Millisecond},
+		{Allowed: false, Limit: 3, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 1500 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Allow() = got %+v, want %+v", got, want)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if d := l.Allow("client"); !d.Allowed {
+		t.Errorf("Allow() = expected request to be allowed after refill")
+	}
+	if d := l.Allow("other"); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("Allow() = expected other client to have separate bucket got %+v", d)
+	}
+}
+
+func TestLimiter_Eviction(t *testing.T) {
+	clock := newFakeClock()
+	l := clock.limiter(NewLimiter(10, time.Second, 0))
+
+	for _, key := range []string{"a", "b", "c"} {
+		l.Allow(key)
+	}
+	clock.Advance(500 * time.Millisecond)
+	l.Allow("a")
+	if got := l.Len(); got != 3 {
+		t.Errorf("Len() = got %d, want %d", got, 3)
+	}
+
+	// buckets b and c are full again and get evicted, bucket a is still refilling
+	clock.Advance(700 * time.Millisecond)
+	l.Allow("d")
+	if got := l.Len(); got != 2 {
+		t.Errorf("Len() = got %d, want %d", got, 2)
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		per   time.Duration
+	}{
+		{name: "should panic for zero limit", limit: 0, per: time.Second},
+		{name: "should panic for negative limit", limit: -1, per: time.Second},
+		{name: "should panic for zero period", limit: 1, per: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewLimiter() = expected panic for limit %d per %s", tt.limit, tt.per)
+				}
+			}()
+			NewLimiter(tt.limit, tt.per, 0)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := newFakeClock()
+	limiter := clock.limiter(NewLimiter(1, time.Minute, 1))
+	handler := RateLimit(limiter, WithKeyFunc(KeyByHeader("X-API-Key")))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+	serve := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if got := serve("key-1").Code; got != http.StatusOK {
+		t.Errorf("RateLimit() = status got %d, want %d", got, http.StatusOK)
+	}
+
+	w := serve("key-1")
+	if got := w.Code; got != http.StatusTooManyRequests {
+		t.Errorf("RateLimit() = status got %d, want %d", got, http.StatusTooManyRequests)
+	}
+	wantHeaders := map[string]string{
+		"Content-Type":        "application/problem+json",
+		"Retry-After":         "60",
+		"RateLimit-Limit":     "1",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+	}
+	for k, v := range wantHeaders {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("RateLimit() = header %s got %q, want %q", k, got, v)
+		}
+	}
+	want := `{"title":"Too Many Requests","status":429,"detail":"rate limit exceeded","instance":"/search"}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("RateLimit() = body got %s, want %s", got, want)
+	}
+
+	if got := serve("key-2").Code; got != http.StatusOK {
+		t.Errorf("RateLimit() = other client status got %d, want %d", got, http.StatusOK)
+	}
+	if got := serve("").Code; got != http.StatusOK {
+		t.Errorf("RateLimit() = anonymous status got %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestKeyByIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/search", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+
+	if got := KeyByIP(r); got != "10.0.0.1" {
+		t.Errorf("KeyByIP() = got %q, want %q", got, "10.0.0.1")
+	}
+}
+
+func TestKeyByAuthorization(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/search", nil)
+	if got := KeyByAuthorization(r); got != "" {
+		t.Errorf("KeyByAuthorization() = got %q for request without header, want empty", got)
+	}
+
+	r.Header.Set("Authorization", "Bearer token")
+	got := KeyByAuthorization(r)
+	if len(got) != 64 || strings.Contains(got, "token") {
+		t.Errorf("KeyByAuthorization() = got %q, want hex encoded hash", got)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// RecoverOption configures the Recover middleware.
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	logger *slog.Logger
+}
+
+// WithRecoverLogger sets the logger used to log the recovered panics, defaults to slog.Default.
+func WithRecoverLogger(logger *slog.Logger) RecoverOption {
+	return func(c *recoverConfig) {
+		c.logger = logger
+	}
+}
+
+// Recover returns middleware that recovers from the panics of the next handler, logs them
+// together with the stack trace and replies to the request with an HTTP 500
+// StatusInternalServerError problem details document using xhttp.InternalServerError.
+//
+// If the response header has already been written, only the panic is logged. Panics with
+// http.ErrAbortHandler are not recovered, so that net/http can abort the response.
+func Recover(opts ...RecoverOption) func(http.Handler) http.Handler {
+	cfg := &recoverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrap(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				logger := cfg.logger
+				if logger == nil {
+					logger = slog.Default()
+				}
+				logger.ErrorContext(r.Context(), "panic recovered",
+					slog.String("panic", fmt.Sprint(v)),
+					slog.String("stack", string(debug.Stack())),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+				)
+				if rw.written() {
+					return
+				}
+				xhttp.InternalServerError(rw, xhttp.NewProblem(http.StatusInternalServerError, ""))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
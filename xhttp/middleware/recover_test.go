@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("should reply with internal server error", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		handler := Recover(WithRecoverLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings", nil))
+
+		if got := w.Code; got != http.StatusInternalServerError {
+			t.Errorf("Recover() = status got %d, want %d", got, http.StatusInternalServerError)
+		}
+		if got := w.Header().Get("Content-Type"); got != xhttp.ContentTypeProblemJSON {
+			t.Errorf("Recover() = content type got %q, want %q", got, xhttp.ContentTypeProblemJSON)
+		}
+		want := `{"title":"Internal Server Error","status":500}`
+		if got := w.Body.String(); got != want {
+			t.Errorf("Recover() = body got %s, want %s", got, want)
+		}
+		if got := logs.String(); !strings.Contains(got, `"panic":"boom"`) || !strings.Contains(got, `"stack":`) {
+			t.Errorf("Recover() = logs got %s, want panic and stack", got)
+		}
+	})
+
+	t.Run("should not write response if header was written", func(t *testing.T) {
+		handler := Recover(WithRecoverLogger(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			}),
+		)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings", nil))
+
+		if got := w.Code; got != http.StatusAccepted {
+			t.Errorf("Recover() = status got %d, want %d", got, http.StatusAccepted)
+		}
+		if got := w.Body.String(); got != "" {
+			t.Errorf("Recover() = expected body to be empty got %s", got)
+		}
+	})
+
+	t.Run("should not recover abort handler panic", func(t *testing.T) {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("Recover() = panic got %v, want %v", v, http.ErrAbortHandler)
+			}
+		}()
+		handler := Recover()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/listings", nil))
+	})
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// HeaderRequestID is the default header used to propagate the request ID.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of the accepted incoming request IDs.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in the context by RequestID middleware,
+// or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the supplied request ID, e.g. to
+// propagate it to the outgoing requests of the background jobs.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDOption configures the RequestID middleware.
+type RequestIDOption func(*requestIDConfig)
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+}
+
+// WithRequestIDHeader sets the header used to read and write the request ID, defaults to HeaderRequestID.
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.header = header
+	}
+}
+
+// WithRequestIDGenerator sets the function that generates new request IDs, defaults to
+// 16 random bytes encoded as hex.
+func WithRequestIDGenerator(generate func() string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.generate = generate
+	}
+}
+
+// RequestID returns middleware that propagates the request ID of the incoming request, or
+// generates a new one if the request does not carry a valid one.
+//
+// The request ID is stored in the request context, see RequestIDFromContext, and written
+// to the response header.
+func RequestID(opts ...RequestIDOption) func(http.Handler) http.Handler {
+	cfg := &requestIDConfig{
+		header:   HeaderRequestID,
+		generate: randomID,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(cfg.header)
+			if !validRequestID(id) {
+				id = cfg.generate()
+			}
+			w.Header().Set(cfg.header, id)
+			next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// validRequestID reports whether id is non-empty, not too long and consists of printable
+// ASCII characters only, so that it is safe to log and to echo in the response header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
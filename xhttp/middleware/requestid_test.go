@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		opts     []RequestIDOption
+		header   string
+		want     string
+	}{
+		{
+			name:     "should propagate incoming request id",
+			incoming: "abc-123",
+			header:   HeaderRequestID,
+			want:     "abc-123",
+		},
+		{
+			name:   "should generate request id",
+			opts:   []RequestIDOption{WithRequestIDGenerator(func() string { return "generated" })},
+			header: HeaderRequestID,
+			want:   "generated",
+		},
+		{
+			name:     "should replace invalid request id",
+			incoming: "abc 123",
+			opts:     []RequestIDOption{WithRequestIDGenerator(func() string { return "generated" })},
+			header:   HeaderRequestID,
+			want:     "generated",
+		},
+		{
+			name:     "should replace too long request id",
+			incoming: strings.Repeat("a", 129),
+			opts:     []RequestIDOption{WithRequestIDGenerator(func() string { return "generated" })},
+			header:   HeaderRequestID,
+			want:     "generated",
+		},
+		{
+			name:     "should use custom header",
+			incoming: "abc-123",
+			opts:     []RequestIDOption{WithRequestIDHeader("X-Correlation-ID")},
+			header:   "X-Correlation-ID",
+			want:     "abc-123",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			handler := RequestID(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = RequestIDFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(tt.header, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if fromContext != tt.want {
+				t.Errorf("RequestID() = context id got %q, want %q", fromContext, tt.want)
+			}
+			if got := w.Header().Get(tt.header); got != tt.want {
+				t.Errorf("RequestID() = header id got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestID_RandomID(t *testing.T) {
+	if got := randomID(); len(got) != 32 || got == randomID() {
+		t.Errorf("randomID() = got %q, want unique 32 characters id", got)
+	}
+}
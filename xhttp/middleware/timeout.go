@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// TimeoutOption configures the Timeout middleware.
+type TimeoutOption func(*timeoutConfig)
+
+type timeoutConfig struct {
+	status int
+}
+
+// WithTimeoutStatus sets the status code of the timed out responses, defaults to HTTP 503
+// StatusServiceUnavailable. Use HTTP 504 StatusGatewayTimeout for the handlers that mostly
+// wait for the upstream services.
+func WithTimeoutStatus(code int) TimeoutOption {
+	return func(c *timeoutConfig) {
+		c.status = code
+	}
+}
+
+// Timeout returns middleware that runs the next handler with the request context deadline
+// set to d. Wrap the individual routes to set different timeouts per route.
+//
+// The response of the handler is buffered until the handler returns or flushes it. If the
+// deadline passes before that, the request is replied with a problem details document and
+// the status set by WithTimeoutStatus. Writes of the handler after the deadline are dropped
+// and return http.ErrHandlerTimeout, so the handler should stop as soon as its context is done.
+//
+// Panics of the handler are propagated to the goroutine serving the request, so they can be
+// handled by the Recover middleware.
+func Timeout(d time.Duration, opts ...TimeoutOption) func(http.Handler) http.Handler {
+	cfg := &timeoutConfig{
+		status: http.StatusServiceUnavailable,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.commit()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				tw.timedOut = true
+				committed := tw.committed
+				tw.mu.Unlock()
+				if !committed {
+					xhttp.WriteError(w, r, xhttp.NewStatusError(cfg.status, "request timed out", ctx.Err()))
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of the handler until it returns or flushes the
+// response, and drops the writes after the timeout.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	status    int
+	committed bool
+	timedOut  bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	if tw.committed {
+		return tw.w.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+// Flush implements http.Flusher interface. It writes the buffered response, the further
+// writes are passed through, so the timed out response is cut short instead of replaced.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.commit()
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by http.ResponseController.
+// Writing to it directly bypasses the timeout guard.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter { return tw.w }
+
+// commit writes the header and the buffered body to the underlying writer, it must be
+// called with mu held.
+func (tw *timeoutWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	if tw.status == 0 {
+		return
+	}
+	tw.w.WriteHeader(tw.status)
+	_, _ = tw.w.Write(tw.buf.Bytes())
+	tw.buf.Reset()
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []TimeoutOption
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+		wantHeader string
+	}{
+		{
+			name: "should write response of handler completed in time",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Listing", "123")
+				xhttp.Created(w, map[string]string{"id": "123"})
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"123"}`,
+			wantHeader: "123",
+		},
+		{
+			name: "should reply with service unavailable if handler times out",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Listing", "123")
+				<-r.Context().Done()
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"title":"Service Unavailable","status":503,"detail":"request timed out","instance":"/search"}`,
+		},
+		{
+			name: "should reply with custom status if handler times out",
+			opts: []TimeoutOption{WithTimeoutStatus(http.StatusGatewayTimeout)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			wantStatus: http.StatusGatewayTimeout,
+			wantBody:   `{"title":"Gateway Timeout","status":504,"detail":"request timed out","instance":"/search"}`,
+		},
+		{
+			name: "should cut flushed response short if handler times out",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("partial"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "partial",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Timeout(20*time.Millisecond, tt.opts...)(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("Timeout() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Timeout() = body got %s, want %s", got, tt.wantBody)
+			}
+			if got := w.Header().Get("X-Listing"); got != tt.wantHeader {
+				t.Errorf("Timeout() = header got %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestTimeout_LateWrites(t *testing.T) {
+	served := make(chan struct{})
+	lateErr := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// writes after the timed out response has been written
+		<-served
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		lateErr <- err
+	})
+	w := httptest.NewRecorder()
+	Timeout(10*time.Millisecond)(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+	close(served)
+
+	if err := <-lateErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("Write() error = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+	if got := w.Code; got != http.StatusServiceUnavailable {
+		t.Errorf("Timeout() = status got %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	Recover()(Timeout(time.Second)(handler)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+
+	if got := w.Code; got != http.StatusInternalServerError {
+		t.Errorf("Timeout() = status got %d, want %d", got, http.StatusInternalServerError)
+	}
+}
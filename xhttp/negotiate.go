@@ -0,0 +1,121 @@
+package xhttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NegotiateContent returns middleware that selects the response encoder from the supplied
+// encoders registry according to the request Accept header. If encoders is nil,
+// DefaultEncoders is used.
+//
+// Responses written by WriteResponse and the helpers built on top of it (OK, Created,
+// BadRequest etc.) are serialized using the selected encoder. If none of the registered
+// encoders is acceptable, the request is replied with an HTTP 406 StatusNotAcceptable
+// problem details document and the next handler is not called.
+func NegotiateContent(encoders *Encoders) func(http.Handler) http.Handler {
+	if encoders == nil {
+		encoders = DefaultEncoders
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			enc, ok := encoders.Negotiate(strings.Join(r.Header.Values("Accept"), ","))
+			if !ok {
+				WriteProblem(w, NewProblem(http.StatusNotAcceptable, fmt.Sprintf(
+					"none of the requested media types is supported, available: %s",
+					strings.Join(encoders.ContentTypes(), ", "),
+				)))
+				return
+			}
+			next.ServeHTTP(&negotiatedWriter{ResponseWriter: w, encoder: enc}, r)
+		})
+	}
+}
+
+// negotiatedWriter carries the encoder selected by NegotiateContent to WriteResponse.
+type negotiatedWriter struct {
+	http.ResponseWriter
+	encoder Encoder
+}
+
+// Unwrap returns the underlying http.ResponseWriter, it is used by http.ResponseController.
+func (w *negotiatedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush implements http.Flusher interface if the underlying writer supports it.
+func (w *negotiatedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// negotiatedEncoder returns the encoder selected by NegotiateContent for w, looking through
+// the chain of the wrapped writers. If no encoder was negotiated it returns false.
+func negotiatedEncoder(w http.ResponseWriter) (Encoder, bool) {
+	for {
+		switch t := w.(type) {
+		case *negotiatedWriter:
+			return t.encoder, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// mediaRange is a single element of the Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses the Accept header value into media ranges. Malformed elements are skipped.
+func parseAccept(accept string) []mediaRange {
+	var res []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			mr.q = q
+		}
+		res = append(res, mr)
+	}
+	return res
+}
+
+// quality returns the q-value of the most specific media range matching the supplied media type.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+
+	q, specificity := 0.0, 0
+	for _, mr := range ranges {
+		s := 0
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 3
+		case mr.typ == typ && mr.subtype == "*":
+			s = 2
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
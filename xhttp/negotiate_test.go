@@ -0,0 +1,87 @@
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateContent(t *testing.T) {
+	handler := NegotiateContent(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w, &dummyResponse{Value: "test"})
+	}))
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "should respond with JSON by default",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"value":"test"}`,
+		},
+		{
+			name:            "should respond with negotiated encoder",
+			accept:          "application/json;q=0.1, application/xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<dummyResponse><Value>test</Value></dummyResponse>`,
+		},
+		{
+			name:            "should respond with not acceptable if no encoder matches",
+			accept:          "image/png",
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: ContentTypeProblemJSON,
+			wantBody: `{"title":"Not Acceptable","status":406,"detail":"none of the requested media types is ` +
+				`supported, available: application/json, application/xml, text/plain; charset=utf-8"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("NegotiateContent() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("NegotiateContent() = content type got %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("NegotiateContent() = vary got %q, want %q", got, "Accept")
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("NegotiateContent() = body got %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestNegotiateContent_WrappedWriter(t *testing.T) {
+	handler := NegotiateContent(NewEncoders(TextEncoder{}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Created(&wrappedWriter{ResponseWriter: w}, "created")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/resource", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("NegotiateContent() = content type got %q, want %q", got, "text/plain; charset=utf-8")
+	}
+	if got := w.Body.String(); got != "created" {
+		t.Errorf("NegotiateContent() = body got %q, want %q", got, "created")
+	}
+}
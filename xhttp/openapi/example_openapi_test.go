@@ -0,0 +1,52 @@
+package openapi_test
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp/openapi"
+)
+
+type Listing struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Price *int   `json:"price,omitempty"`
+}
+
+type GetListing struct {
+	ID string `path:"id" validate:"required"`
+}
+
+type CreateListing struct {
+	Title string `json:"title" validate:"required,min=3,max=100"`
+	Price *int   `json:"price" validate:"min=0"`
+}
+
+func ExampleHandle() {
+	spec := openapi.New("Listings", "1.0.0")
+	router := xhttp.NewRouter()
+	api := router.Group("/api/v1")
+
+	openapi.Handle(spec, api, http.MethodGet, "/listings/{id}", func(ctx context.Context, req GetListing) (Listing, error) {
+		return Listing{ID: req.ID}, nil
+	}, openapi.WithSummary("Returns a listing"), openapi.WithResponse(http.StatusNotFound, "Listing not found"))
+	openapi.Handle(spec, api, http.MethodPost, "/listings", func(ctx context.Context, req CreateListing) (Listing, error) {
+		return Listing{ID: "1", Title: req.Title, Price: req.Price}, nil
+	}, openapi.WithStatus(http.StatusCreated), openapi.WithTags("listings"))
+	router.Get("/openapi.json", spec.Handler())
+
+	_ = http.ListenAndServe(":8080", router)
+}
+
+func ExampleSpec_WriteFile() {
+	// e.g. in a command run by //go:generate go run ./cmd/openapi
+	spec := openapi.New("Listings", "1.0.0", openapi.WithDescription("Real estate listings."))
+	openapi.Describe[GetListing, Listing](spec, http.MethodGet, "/api/v1/listings/{id}")
+	openapi.Describe[CreateListing, Listing](spec, http.MethodPost, "/api/v1/listings", openapi.WithStatus(http.StatusCreated))
+
+	if err := spec.WriteFile("openapi.json"); err != nil {
+		log.Fatal(err)
+	}
+}
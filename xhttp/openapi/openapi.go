@@ -0,0 +1,450 @@
+// Package openapi generates OpenAPI 3.1 documents of the typed handlers of xhttp.Handle.
+//
+// The operations are described by their request and response Go types. The schemas are
+// generated by reflecting over the types: the fields are named by the `json` tags, the
+// pointer and omitempty fields are optional, and the `validate` tag rules of xvalidate are
+// translated into the constraints, e.g. minLength or enum. The fields of the request tagged
+// with `path` and `query` are described as the parameters, the rest as the JSON body.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the schemas referenced by the operations.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Operation describes an API operation, i.e. a method of a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// clone returns a deep copy of the operation.
+func (op *Operation) clone() *Operation {
+	c := *op
+	c.Tags = slices.Clone(op.Tags)
+	c.Parameters = nil
+	for _, p := range op.Parameters {
+		param := *p
+		param.Schema = p.Schema.clone()
+		c.Parameters = append(c.Parameters, &param)
+	}
+	if op.RequestBody != nil {
+		c.RequestBody = &RequestBody{Required: op.RequestBody.Required, Content: cloneContent(op.RequestBody.Content)}
+	}
+	c.Responses = make(map[string]*Response, len(op.Responses))
+	for code, res := range op.Responses {
+		c.Responses[code] = &Response{Description: res.Description, Content: cloneContent(res.Content)}
+	}
+	return &c
+}
+
+// Parameter describes a path or a query parameter of an operation.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the request body of an operation.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the content of a request or a response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// cloneContent returns a deep copy of the content of a request or a response body.
+func cloneContent(content map[string]MediaType) map[string]MediaType {
+	if content == nil {
+		return nil
+	}
+	c := make(map[string]MediaType, len(content))
+	for mt, m := range content {
+		c[mt] = MediaType{Schema: m.Schema.clone()}
+	}
+	return c
+}
+
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeProblem = "application/problem+json"
+	problemSchemaName  = "Problem"
+)
+
+// SpecOption configures Spec.
+type SpecOption func(*Spec)
+
+// WithDescription sets the description of the API.
+func WithDescription(description string) SpecOption {
+	return func(s *Spec) {
+		s.info.Description = description
+	}
+}
+
+// Spec collects the operations of an API and generates its OpenAPI document.
+//
+// Spec is safe for concurrent use.
+type Spec struct {
+	mu        sync.Mutex
+	info      Info
+	paths     map[string]map[string]*Operation
+	generator *schemaGenerator
+}
+
+// New returns a new Spec of the API with the title and the version, e.g. "1.2.0".
+func New(title, version string, opts ...SpecOption) *Spec {
+	s := &Spec{
+		info:      Info{Title: title, Version: version},
+		paths:     make(map[string]map[string]*Operation),
+		generator: newSchemaGenerator(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Document returns the OpenAPI document of the described operations. The document is a
+// copy, modifying it does not affect the Spec.
+func (s *Spec) Document() *Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Paths:   make(map[string]map[string]*Operation, len(s.paths)),
+	}
+	for p, operations := range s.paths {
+		doc.Paths[p] = make(map[string]*Operation, len(operations))
+		for method, op := range operations {
+			doc.Paths[p][method] = op.clone()
+		}
+	}
+	doc.Components.Schemas = make(map[string]*Schema, len(s.generator.schemas))
+	for name, schema := range s.generator.schemas {
+		doc.Components.Schemas[name] = schema.clone()
+	}
+	return doc
+}
+
+// marshal returns the JSON encoding of the document, indented by two spaces.
+func (s *Spec) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.Document()); err != nil {
+		return nil, fmt.Errorf("encoding openapi document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Handler returns an http.Handler serving the document as JSON, e.g. at "/openapi.json".
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := s.marshal()
+		if err != nil {
+			xhttp.WriteError(w, r, xhttp.ErrInternal(err))
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write(data)
+	})
+}
+
+// WriteFile writes the JSON document to the named file, e.g. from a command run by
+// go generate.
+func (s *Spec) WriteFile(name string) error {
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(name, data, 0o644); err != nil {
+		return fmt.Errorf("writing openapi document: %w", err)
+	}
+	return nil
+}
+
+// OperationOption configures the operation described by Describe or Handle.
+type OperationOption func(*operationConfig)
+
+type operationConfig struct {
+	op             *Operation
+	status         int
+	responses      map[int]string
+	handlerOptions []xhttp.HandlerOption
+}
+
+// WithSummary sets the summary of the operation.
+func WithSummary(summary string) OperationOption {
+	return func(c *operationConfig) {
+		c.op.Summary = summary
+	}
+}
+
+// WithOperationDescription sets the description of the operation.
+func WithOperationDescription(description string) OperationOption {
+	return func(c *operationConfig) {
+		c.op.Description = description
+	}
+}
+
+// WithTags sets the tags grouping the operation.
+func WithTags(tags ...string) OperationOption {
+	return func(c *operationConfig) {
+		c.op.Tags = tags
+	}
+}
+
+// WithOperationID sets the unique identifier of the operation, e.g. "getListing".
+func WithOperationID(id string) OperationOption {
+	return func(c *operationConfig) {
+		c.op.OperationID = id
+	}
+}
+
+// WithStatus sets the status code of the successful response, defaults to HTTP 200 StatusOK.
+// Handle passes it to the handler using xhttp.WithStatus.
+func WithStatus(code int) OperationOption {
+	return func(c *operationConfig) {
+		c.status = code
+	}
+}
+
+// WithResponse describes an error response of the operation, e.g. HTTP 404 StatusNotFound,
+// replied with a problem details document. Other errors are described by the default response.
+func WithResponse(code int, description string) OperationOption {
+	return func(c *operationConfig) {
+		c.responses[code] = description
+	}
+}
+
+// WithHandlerOptions sets the options of the handler registered by Handle.
+func WithHandlerOptions(opts ...xhttp.HandlerOption) OperationOption {
+	return func(c *operationConfig) {
+		c.handlerOptions = append(c.handlerOptions, opts...)
+	}
+}
+
+// Describe adds the operation of the method and the path pattern, e.g. "/listings/{id}", to
+// the document. Req and Resp are the types of the request and the response of the handler,
+// see xhttp.Handle. It panics if the operation is already described.
+func Describe[Req, Resp any](s *Spec, method, pattern string, opts ...OperationOption) {
+	cfg := newOperationConfig(opts)
+	describe(s, method, pattern, reflect.TypeOf((*Req)(nil)).Elem(), reflect.TypeOf((*Resp)(nil)).Elem(), cfg)
+}
+
+// Handle registers the handler of fn, see xhttp.Handle, for the method and the pattern in the
+// router, and describes its operation in the document, see Describe.
+func Handle[Req, Resp any](s *Spec, router *xhttp.Router, method, pattern string, fn func(ctx context.Context, req Req) (Resp, error), opts ...OperationOption) {
+	cfg := newOperationConfig(opts)
+	handlerOpts := append([]xhttp.HandlerOption{xhttp.WithStatus(cfg.status)}, cfg.handlerOptions...)
+	router.Handle(method, pattern, xhttp.Handle(fn, handlerOpts...))
+	describe(s, method, router.Prefix()+pattern, reflect.TypeOf((*Req)(nil)).Elem(), reflect.TypeOf((*Resp)(nil)).Elem(), cfg)
+}
+
+func newOperationConfig(opts []OperationOption) *operationConfig {
+	cfg := &operationConfig{
+		op:        &Operation{Responses: make(map[string]*Response)},
+		status:    http.StatusOK,
+		responses: make(map[int]string),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+var patternParam = regexp.MustCompile(`\{([^}]+?)(\.\.\.)?\}`)
+
+// describe adds the operation to the document.
+func describe(s *Spec, method, pattern string, req, resp reflect.Type, cfg *operationConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	method = strings.ToLower(method)
+	p := patternParam.ReplaceAllString(pattern, "{$1}")
+	if _, ok := s.paths[p][method]; ok {
+		panic(fmt.Sprintf("openapi: operation %s %s is already described", strings.ToUpper(method), p))
+	}
+
+	op := cfg.op
+	g := s.generator
+	op.Parameters, op.RequestBody = g.request(req, method, pattern)
+	op.Responses[strconv.Itoa(cfg.status)] = g.response(resp, cfg.status)
+	for code, description := range cfg.responses {
+		op.Responses[strconv.Itoa(code)] = g.problemResponse(description)
+	}
+	op.Responses["default"] = g.problemResponse("Error")
+
+	if s.paths[p] == nil {
+		s.paths[p] = make(map[string]*Operation)
+	}
+	s.paths[p][method] = op
+}
+
+// request returns the parameters and the body of the request type. The parameters of the
+// pattern not bound by the request are described as strings.
+func (g *schemaGenerator) request(typ reflect.Type, method, pattern string) ([]*Parameter, *RequestBody) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	var params []*Parameter
+	bound := make(map[string]bool)
+	if typ.Kind() == reflect.Struct {
+		params = g.parameters(typ)
+		for _, param := range params {
+			bound[param.Name] = param.In == "path"
+		}
+	}
+	var pathParams []*Parameter
+	for _, m := range patternParam.FindAllStringSubmatch(pattern, -1) {
+		if !bound[m[1]] {
+			pathParams = append(pathParams, &Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	params = append(pathParams, params...)
+
+	switch method {
+	case "get", "head", "delete", "options":
+		return params, nil
+	}
+	var body *Schema
+	switch {
+	case typ.Kind() == reflect.Struct && len(params) > 0:
+		body = g.structSchema(typ, isParameter)
+		if len(body.Properties) == 0 {
+			return params, nil
+		}
+	case typ.Kind() == reflect.Struct && typ.NumField() == 0:
+		return params, nil
+	default:
+		body = g.schema(typ)
+	}
+	return params, &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{contentTypeJSON: {Schema: body}},
+	}
+}
+
+// parameters returns the path and the query parameters of the fields of the struct type.
+func (g *schemaGenerator) parameters(typ reflect.Type) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		for _, in := range []string{"path", "query"} {
+			name, ok := field.Tag.Lookup(in)
+			if !ok || name == "-" {
+				continue
+			}
+			schema := g.schema(field.Type)
+			required := applyRules(schema, field.Type, field.Tag.Get("validate"))
+			params = append(params, &Parameter{Name: name, In: in, Required: required || in == "path", Schema: schema})
+		}
+	}
+	return params
+}
+
+// isParameter reports whether the field is bound from the path or the query parameters.
+func isParameter(field reflect.StructField) bool {
+	for _, in := range []string{"path", "query"} {
+		if name, ok := field.Tag.Lookup(in); ok && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// response returns the successful response of the response type. The responses of the empty
+// struct type and of HTTP 204 StatusNoContent have no content.
+func (g *schemaGenerator) response(typ reflect.Type, status int) *Response {
+	res := &Response{Description: http.StatusText(status)}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if status == http.StatusNoContent || (typ.Kind() == reflect.Struct && typ.NumField() == 0) {
+		return res
+	}
+	res.Content = map[string]MediaType{contentTypeJSON: {Schema: g.schema(typ)}}
+	return res
+}
+
+// problemSchema is the schema of the problem details document, see xhttp.Problem.
+func problemSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":     {Type: "string", Format: "uri-reference"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer", Format: "int64"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string", Format: "uri-reference"},
+		},
+		AdditionalProperties: &Schema{},
+	}
+}
+
+// problemResponse returns an error response replied with a problem details document, see
+// xhttp.WriteError.
+func (g *schemaGenerator) problemResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]MediaType{
+			contentTypeProblem: {Schema: &Schema{Ref: "#/components/schemas/" + problemSchemaName}},
+		},
+	}
+}
@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xhttp"
+)
+
+var componentNamePattern = regexp.MustCompile(`^#/components/schemas/[A-Za-z0-9._-]+$`)
+
+type getListing struct {
+	ID     int64  `path:"id"`
+	Fields string `query:"fields"`
+}
+
+type searchListings struct {
+	Query string   `query:"q" validate:"required,min=2"`
+	Kinds []string `query:"kind"`
+}
+
+type updateListing struct {
+	ID    int64  `path:"id" json:"-"`
+	Title string `json:"title" validate:"required"`
+	Price *int   `json:"price"`
+}
+
+type createListing struct {
+	Title string `json:"title" validate:"required"`
+}
+
+func newTestSpec() *Spec {
+	s := New("Listings", "1.0.0", WithDescription("Real estate listings."))
+	router := xhttp.NewRouter().Group("/api/v1")
+	Handle(s, router, http.MethodGet, "/listings/{id}", func(ctx context.Context, req getListing) (*listing, error) {
+		return nil, nil
+	}, WithOperationID("getListing"), WithTags("listings"), WithResponse(http.StatusNotFound, "Listing not found"))
+	Handle(s, router, http.MethodPost, "/listings", func(ctx context.Context, req createListing) (listing, error) {
+		return listing{}, nil
+	}, WithStatus(http.StatusCreated), WithSummary("Creates a listing"))
+	Describe[updateListing, struct{}](s, http.MethodPatch, "/api/v1/listings/{id}", WithStatus(http.StatusNoContent))
+	Describe[searchListings, []listing](s, http.MethodGet, "/api/v1/listings")
+	Describe[struct{}, []byte](s, http.MethodGet, "/api/v1/files/{path...}")
+	return s
+}
+
+func TestDescribe(t *testing.T) {
+	s := newTestSpec()
+
+	doc := s.Document()
+
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Listings" || doc.Info.Description != "Real estate listings." {
+		t.Errorf("Document() = got %q %+v, want 3.1.0 and info", doc.OpenAPI, doc.Info)
+	}
+	if _, ok := doc.Components.Schemas["listing"]; !ok {
+		t.Errorf("Document() = components got %v, want listing", doc.Components.Schemas)
+	}
+
+	t.Run("should describe path and query parameters", func(t *testing.T) {
+		op := doc.Paths["/api/v1/listings/{id}"]["get"]
+
+		assertJSON(t, op, `{
+  "operationId": "getListing",
+  "tags": ["listings"],
+  "parameters": [
+    {"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+    {"name": "fields", "in": "query", "schema": {"type": "string"}}
+  ],
+  "responses": {
+    "200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/listing"}}}},
+    "404": {"description": "Listing not found", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+    "default": {"description": "Error", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+  }
+}`)
+	})
+
+	t.Run("should describe required query parameters", func(t *testing.T) {
+		op := doc.Paths["/api/v1/listings"]["get"]
+
+		assertJSON(t, op.Parameters, `[
+  {"name": "q", "in": "query", "required": true, "schema": {"type": "string", "minLength": 2}},
+  {"name": "kind", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}}
+]`)
+		assertJSON(t, op.Responses["200"].Content["application/json"].Schema, `{"type": "array", "items": {"$ref": "#/components/schemas/listing"}}`)
+	})
+
+	t.Run("should describe request body", func(t *testing.T) {
+		op := doc.Paths["/api/v1/listings"]["post"]
+
+		if op.Summary != "Creates a listing" {
+			t.Errorf("Describe() = summary got %q, want %q", op.Summary, "Creates a listing")
+		}
+		assertJSON(t, op.RequestBody, `{"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/createListing"}}}}`)
+		if _, ok := op.Responses["201"]; !ok {
+			t.Errorf("Describe() = responses got %v, want 201", op.Responses)
+		}
+	})
+
+	t.Run("should describe request body without parameters", func(t *testing.T) {
+		op := doc.Paths["/api/v1/listings/{id}"]["patch"]
+
+		assertJSON(t, op.RequestBody, `{"required": true, "content": {"application/json": {"schema": {
+  "type": "object",
+  "properties": {"title": {"type": "string"}, "price": {"type": "integer", "format": "int64"}},
+  "required": ["title"]
+}}}}`)
+		assertJSON(t, op.Responses["204"], `{"description": "No Content"}`)
+	})
+
+	t.Run("should describe pattern parameters", func(t *testing.T) {
+		op := doc.Paths["/api/v1/files/{path}"]["get"]
+
+		assertJSON(t, op.Parameters, `[{"name": "path", "in": "path", "required": true, "schema": {"type": "string"}}]`)
+		if op.RequestBody != nil {
+			t.Errorf("Describe() = request body got %+v, want nil", op.RequestBody)
+		}
+	})
+
+	t.Run("should panic for duplicate operation", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Describe() = expected panic for duplicate operation")
+			}
+		}()
+		Describe[getListing, listing](s, http.MethodGet, "/api/v1/listings/{id}")
+	})
+}
+
+func TestSpec_Document(t *testing.T) {
+	s := newTestSpec()
+
+	doc := s.Document()
+	doc.Paths["/api/v1/listings/{id}"]["get"].Parameters[0].Schema.Type = "string"
+	doc.Components.Schemas["listing"].Properties["title"].MaxLength = nil
+
+	doc = s.Document()
+	if got := doc.Paths["/api/v1/listings/{id}"]["get"].Parameters[0].Schema.Type; got != "integer" {
+		t.Errorf("Document() = parameter type got %q, want %q", got, "integer")
+	}
+	if got := doc.Components.Schemas["listing"].Properties["title"].MaxLength; got == nil || *got != 100 {
+		t.Errorf("Document() = title max length got %v, want 100", got)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	s := New("Listings", "1.0.0")
+	router := xhttp.NewRouter()
+	Handle(s, router, http.MethodPost, "/listings", func(ctx context.Context, req createListing) (createListing, error) {
+		return req, nil
+	}, WithStatus(http.StatusCreated))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(`{"title":"Flat"}`)))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("ServeHTTP() = status got %d, want %d", w.Code, http.StatusCreated)
+	}
+	if got, want := w.Body.String(), `{"title":"Flat"}`; got != want {
+		t.Errorf("ServeHTTP() = body got %s, want %s", got, want)
+	}
+}
+
+func TestSpec_Handler(t *testing.T) {
+	s := newTestSpec()
+	w := httptest.NewRecorder()
+
+	s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("ServeHTTP() = content type got %q, want application/json", got)
+	}
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(doc.Paths) != 3 {
+		t.Errorf("ServeHTTP() = paths got %d, want 3", len(doc.Paths))
+	}
+}
+
+func TestSpec_WriteFile(t *testing.T) {
+	s := newTestSpec()
+	name := filepath.Join(t.TempDir(), "openapi.json")
+
+	if err := s.WriteFile(name); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), "{\n  \"openapi\": \"3.1.0\",") {
+		t.Errorf("WriteFile() = got %s, want indented document", data)
+	}
+	if err = s.WriteFile(filepath.Join(name, "missing", "openapi.json")); err == nil {
+		t.Errorf("WriteFile() error = nil, want error for invalid path")
+	}
+}
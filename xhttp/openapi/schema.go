@@ -0,0 +1,331 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
+)
+
+// Schema is a JSON Schema object of the OpenAPI document.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// clone returns a deep copy of the schema.
+func (s *Schema) clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			c.Properties[name] = prop.clone()
+		}
+	}
+	c.Required = slices.Clone(s.Required)
+	c.Items = s.Items.clone()
+	c.AdditionalProperties = s.AdditionalProperties.clone()
+	c.Enum = slices.Clone(s.Enum)
+	c.Minimum = clonePtr(s.Minimum)
+	c.Maximum = clonePtr(s.Maximum)
+	c.MinLength = clonePtr(s.MinLength)
+	c.MaxLength = clonePtr(s.MaxLength)
+	c.MinItems = clonePtr(s.MinItems)
+	c.MaxItems = clonePtr(s.MaxItems)
+	c.MinProperties = clonePtr(s.MinProperties)
+	c.MaxProperties = clonePtr(s.MaxProperties)
+	return &c
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator generates the schemas of the Go types. The named struct types are added
+// to the components of the document and referenced.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		// the problem details schema is reserved, the types of the same name are prefixed
+		schemas: map[string]*Schema{problemSchemaName: problemSchema()},
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the schema of the type.
+func (g *schemaGenerator) schema(typ reflect.Type) *Schema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case typ == rawMessageType:
+		return &Schema{}
+	case implements(typ, jsonMarshalerType):
+		return &Schema{}
+	case implements(typ, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptrTo(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 && typ.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ, nil)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(typ)}
+	}
+	// interfaces and the types not supported by encoding/json
+	return &Schema{}
+}
+
+// component adds the schema of the named struct type to the components, unless already
+// added, and returns its name.
+func (g *schemaGenerator) component(typ reflect.Type) string {
+	if name, ok := g.names[typ]; ok {
+		return name
+	}
+	name := componentName(typ.Name())
+	if _, ok := g.schemas[name]; ok {
+		name = componentName(path.Base(typ.PkgPath()) + "." + typ.Name())
+	}
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = componentName(fmt.Sprintf("%s%d", typ.Name(), i))
+	}
+	// registered before generating the fields, so the recursive types reference themselves
+	g.names[typ] = name
+	g.schemas[name] = &Schema{}
+	g.schemas[name] = g.structSchema(typ, nil)
+	return name
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// componentName returns the name of the component schema, e.g. of the generic types.
+func componentName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// structSchema returns the object schema of the struct type. Fields for which skip returns
+// true are left out.
+func (g *schemaGenerator) structSchema(typ reflect.Type, skip func(reflect.StructField) bool) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, typ, skip)
+	return s
+}
+
+// addFields adds the properties of the fields of the struct type to s, the fields of the
+// embedded structs are promoted as by encoding/json.
+func (g *schemaGenerator) addFields(s *Schema, typ reflect.Type, skip func(reflect.StructField) bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (skip != nil && skip(field)) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := field.Type
+		if field.Anonymous && name == "" {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft, skip)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := g.schema(ft)
+		if hasOption(opts, "string") && prop.Type != "" && prop.Type != "string" {
+			prop = &Schema{Type: "string"}
+		}
+		required := applyRules(prop, ft, field.Tag.Get("validate"))
+		s.Properties[name] = prop
+		if required || (ft.Kind() != reflect.Pointer && !hasOption(opts, "omitempty")) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyRules sets the constraints of the `validate` tag rules on s, see xvalidate.Validate.
+// It reports whether the tag has the required rule.
+func applyRules(s *Schema, typ reflect.Type, tag string) (required bool) {
+	t, err := xvalidate.ParseTag(tag)
+	if t == nil || err != nil {
+		// the invalid tags are reported by xvalidate.Validate when the requests are validated
+		return false
+	}
+	applyTag(s, typ, t)
+	return t.Required
+}
+
+// applyTag sets the constraints of the parsed tag on s, the rules following dive are set on
+// the items or the additional properties.
+func applyTag(s *Schema, typ reflect.Type, t *xvalidate.Tag) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	for _, r := range t.Rules {
+		switch r.Name {
+		case "min", "max", "len":
+			applySize(s, typ, r.Name, r.Param)
+		case "oneof":
+			for _, v := range strings.Fields(r.Param) {
+				s.Enum = append(s.Enum, enumValue(typ, v))
+			}
+		case "regexp":
+			s.Pattern = r.Param
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		}
+	}
+	if t.Dive == nil {
+		return
+	}
+	switch {
+	case s.Items != nil:
+		applyTag(s.Items, typ.Elem(), t.Dive)
+	case s.AdditionalProperties != nil:
+		applyTag(s.AdditionalProperties, typ.Elem(), t.Dive)
+	}
+}
+
+// applySize sets the constraint of the min, max or len rule according to the type.
+func applySize(s *Schema, typ reflect.Type, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if typ == durationType {
+		d, durErr := time.ParseDuration(param)
+		n, err = float64(d), durErr
+	}
+	if err != nil {
+		return
+	}
+	isMin, isMax := rule != "max", rule != "min"
+	switch typ.Kind() {
+	case reflect.String:
+		setBounds(&s.MinLength, &s.MaxLength, int(n), isMin, isMax)
+	case reflect.Slice, reflect.Array:
+		setBounds(&s.MinItems, &s.MaxItems, int(n), isMin, isMax)
+	case reflect.Map:
+		setBounds(&s.MinProperties, &s.MaxProperties, int(n), isMin, isMax)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		setBounds(&s.Minimum, &s.Maximum, n, isMin, isMax)
+	}
+}
+
+func setBounds[T any](min, max **T, n T, isMin, isMax bool) {
+	if isMin {
+		*min = ptrTo(n)
+	}
+	if isMax {
+		*max = ptrTo(n)
+	}
+}
+
+// enumValue returns the oneof value as a number for the numeric types.
+func enumValue(typ reflect.Type, v string) any {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// implements reports whether typ or a pointer to typ implements the interface iface.
+func implements(typ, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface)
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	return ptrTo(*p)
+}
@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City   string  `json:"city" validate:"required,min=2"`
+	Street *string `json:"street"`
+}
+
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children,omitempty"`
+}
+
+type audit struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type listing struct {
+	audit
+	ID       int64             `json:"id,string"`
+	Title    string            `json:"title" validate:"min=3,max=100"`
+	Price    float64           `json:"price" validate:"min=0"`
+	Rooms    uint8             `json:"rooms,omitempty" validate:"max=20"`
+	Kind     string            `json:"kind" validate:"oneof=flat house"`
+	Floor    int               `json:"floor,omitempty" validate:"oneof=0 1 2"`
+	Email    string            `json:"email,omitempty" validate:"email"`
+	Code     string            `json:"code,omitempty" validate:"regexp=^[a-z]{2,3}$"`
+	Photos   []string          `json:"photos,omitempty" validate:"max=10,dive,url"`
+	Labels   map[string]string `json:"labels,omitempty" validate:"dive,min=1"`
+	TTL      time.Duration     `json:"ttl,omitempty" validate:"min=1s"`
+	Address  *address          `json:"address" validate:"required"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Extra    any               `json:"extra,omitempty"`
+	Internal string            `json:"-"`
+	NoTag    bool
+	hidden   bool
+}
+
+func TestSchemaGenerator(t *testing.T) {
+	t.Run("should generate schema of struct", func(t *testing.T) {
+		g := newSchemaGenerator()
+
+		got := g.schema(reflect.TypeOf(&listing{}))
+
+		if want := (&Schema{Ref: "#/components/schemas/listing"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("schema() = got %+v, want %+v", got, want)
+		}
+		want := `{
+  "type": "object",
+  "properties": {
+    "NoTag": {"type": "boolean"},
+    "address": {"$ref": "#/components/schemas/address"},
+    "code": {"type": "string", "pattern": "^[a-z]{2,3}$"},
+    "createdAt": {"type": "string", "format": "date-time"},
+    "data": {"type": "string", "format": "byte"},
+    "email": {"type": "string", "format": "email"},
+    "extra": {},
+    "floor": {"type": "integer", "format": "int64", "enum": [0, 1, 2]},
+    "id": {"type": "string"},
+    "kind": {"type": "string", "enum": ["flat", "house"]},
+    "labels": {"type": "object", "additionalProperties": {"type": "string", "minLength": 1}},
+    "photos": {"type": "array", "items": {"type": "string", "format": "uri"}, "maxItems": 10},
+    "price": {"type": "number", "format": "double", "minimum": 0},
+    "raw": {},
+    "rooms": {"type": "integer", "minimum": 0, "maximum": 20},
+    "title": {"type": "string", "minLength": 3, "maxLength": 100},
+    "ttl": {"type": "integer", "format": "int64", "minimum": 1000000000}
+  },
+  "required": ["createdAt", "id", "title", "price", "kind", "address", "NoTag"]
+}`
+		assertJSON(t, g.schemas["listing"], want)
+		assertJSON(t, g.schemas["address"], `{
+  "type": "object",
+  "properties": {
+    "city": {"type": "string", "minLength": 2},
+    "street": {"type": "string"}
+  },
+  "required": ["city"]
+}`)
+	})
+
+	t.Run("should reference recursive struct", func(t *testing.T) {
+		g := newSchemaGenerator()
+
+		g.schema(reflect.TypeOf(node{}))
+
+		assertJSON(t, g.schemas["node"], `{
+  "type": "object",
+  "properties": {
+    "children": {"type": "array", "items": {"$ref": "#/components/schemas/node"}},
+    "name": {"type": "string"}
+  },
+  "required": ["name"]
+}`)
+	})
+
+	t.Run("should prefix name of colliding struct", func(t *testing.T) {
+		type Problem struct {
+			Code string `json:"code"`
+		}
+		g := newSchemaGenerator()
+
+		got := g.schema(reflect.TypeOf(Problem{}))
+
+		if want := "#/components/schemas/openapi.Problem"; got.Ref != want {
+			t.Errorf("schema() = ref got %q, want %q", got.Ref, want)
+		}
+	})
+
+	t.Run("should name generic struct", func(t *testing.T) {
+		g := newSchemaGenerator()
+
+		got := g.schema(reflect.TypeOf(page[address]{}))
+
+		if _, ok := g.schemas[got.Ref[len("#/components/schemas/"):]]; !ok {
+			t.Errorf("schema() = ref %q not in components %v", got.Ref, g.schemas)
+		}
+		if !componentNamePattern.MatchString(got.Ref) {
+			t.Errorf("schema() = ref got %q, want valid component name", got.Ref)
+		}
+	})
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+// assertJSON compares the JSON encoding of got with the JSON document want.
+func assertJSON(t *testing.T, got any, want string) {
+	t.Helper()
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var gotValue, wantValue any
+	if err = json.Unmarshal(data, &gotValue); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err = json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("json.Unmarshal() want error = %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,197 @@
+package xhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"git.naspersclassifieds.com/olxeu/realestate/go-toolkit/x/xvalidate"
+)
+
+// ContentTypeProblemJSON is the media type of the problem details documents
+// as defined in RFC 9457 (formerly RFC 7807).
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Problem represents a problem details document as defined in RFC 9457 (formerly RFC 7807).
+//
+// Extensions are serialized as additional top level members of the document. Extension
+// members that collide with the standard members are ignored.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type. When omitted,
+	// its value is assumed to be "about:blank".
+	Type string `json:"type,omitempty"`
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title,omitempty"`
+	// Status is the HTTP status code generated by the origin server for this occurrence of the problem.
+	Status int `json:"status,omitempty"`
+	// Detail is a human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds additional members of the problem details document.
+	Extensions map[string]any `json:"-"`
+}
+
+// NewProblem returns a new Problem with the supplied status, the status text as a title
+// and the supplied detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// ValidationProblem returns a new Problem with an HTTP 422 StatusUnprocessableEntity status
+// and the supplied field errors set as the "errors" extension member.
+func ValidationProblem(errs ...*FieldError) *Problem {
+	return NewProblem(http.StatusUnprocessableEntity, "request validation failed").WithErrors(errs...)
+}
+
+// With sets the extension member key to value and returns the Problem to allow chaining.
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// WithErrors sets the supplied field errors as the "errors" extension member and
+// returns the Problem to allow chaining. If no errors are supplied, Problem is returned unchanged.
+func (p *Problem) WithErrors(errs ...*FieldError) *Problem {
+	if len(errs) == 0 {
+		return p
+	}
+	return p.With("errors", errs)
+}
+
+// Error implements the error interface, so that Problem can be returned as an error.
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		return title
+	}
+	return fmt.Sprintf("%s: %s", title, p.Detail)
+}
+
+// MarshalJSON implements json.Marshaler interface. Standard members take precedence over
+// the extension members with the same name.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	standard, err := json.Marshal((*problem)(p))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Extensions) == 0 {
+		return standard, nil
+	}
+	members := make(map[string]json.RawMessage, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling extension member %q: %w", k, err)
+		}
+		members[k] = b
+	}
+	var std map[string]json.RawMessage
+	if err := json.Unmarshal(standard, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface. Members that are not part of the
+// standard problem details document are collected into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type problem Problem
+	var std problem
+	if err := json.Unmarshal(data, &std); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, k)
+	}
+	*p = Problem(std)
+	for k, raw := range members {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		p.With(k, v)
+	}
+	return nil
+}
+
+// FieldError describes a failure of a single request field, e.g. failed validation or binding.
+type FieldError struct {
+	// Field is the path of the field, e.g. "address.city" or "rooms[1]".
+	Field string `json:"field"`
+	// Message is a human-readable explanation of the failure.
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// FieldErrors collects all the FieldError values from the err tree, including the errors
+// joined using errors.Join function (e.g. xslices.MapWithError with fail-fast disabled).
+// The xvalidate.FieldError values are converted to FieldError values.
+func FieldErrors(err error) []*FieldError {
+	if err == nil {
+		return nil
+	}
+	switch e := err.(type) {
+	case *FieldError:
+		return []*FieldError{e}
+	case *xvalidate.FieldError:
+		return []*FieldError{{Field: e.Field, Message: e.Message}}
+	case interface{ Unwrap() []error }:
+		var res []*FieldError
+		for _, err := range e.Unwrap() {
+			res = append(res, FieldErrors(err)...)
+		}
+		return res
+	case interface{ Unwrap() error }:
+		return FieldErrors(e.Unwrap())
+	}
+	return nil
+}
+
+// WriteProblem replies to the request with the supplied Problem serialized as the
+// application/problem+json document and the HTTP code set to the Problem status.
+// It does not otherwise end the request; the caller should ensure no further
+// writes are done to w.
+//
+// If the Problem status is not set, HTTP 500 StatusInternalServerError is used.
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		// extension members failed to serialize, fallback to the standard members only
+		std := *p
+		std.Extensions = nil
+		b, _ = json.Marshal(&std)
+	}
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(b)
+}
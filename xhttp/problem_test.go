@@ -0,0 +1,138 @@
+package xhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	t.Run("should serialize standard members only if extensions are empty", func(t *testing.T) {
+		got, err := json.Marshal(NewProblem(http.StatusNotFound, "listing 123 not found"))
+		if err != nil {
+			t.Fatalf("MarshalJSON() error = %v", err)
+		}
+		want := `{"title":"Not Found","status":404,"detail":"listing 123 not found"}`
+		if string(got) != want {
+			t.Errorf("MarshalJSON() = got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should serialize extensions as top level members", func(t *testing.T) {
+		p := NewProblem(http.StatusForbidden, "not enough credit").
+			With("balance", 30).
+			With("title", "overridden")
+		p.Type = "https://example.com/probs/out-of-credit"
+		got, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("MarshalJSON() error = %v", err)
+		}
+		want := `{"balance":30,"detail":"not enough credit","status":403,"title":"Forbidden",` +
+			`"type":"https://example.com/probs/out-of-credit"}`
+		if string(got) != want {
+			t.Errorf("MarshalJSON() = got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should return error if extension member serialization fails", func(t *testing.T) {
+		if _, err := json.Marshal(NewProblem(http.StatusBadRequest, "").With("nan", math.NaN())); err == nil {
+			t.Errorf("MarshalJSON() expected error")
+		}
+	})
+}
+
+func TestProblem_UnmarshalJSON(t *testing.T) {
+	var got Problem
+	data := `{"type":"about:blank","title":"Conflict","status":409,"detail":"state conflict","version":"3"}`
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	want := Problem{
+		Type:       "about:blank",
+		Title:      "Conflict",
+		Status:     http.StatusConflict,
+		Detail:     "state conflict",
+		Extensions: map[string]any{"version": "3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalJSON() = got %+v, want %+v", got, want)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	price := &FieldError{Field: "price", Message: "must be positive"}
+	rooms := &FieldError{Field: "rooms", Message: "is required"}
+
+	tests := []struct {
+		name string
+		err  error
+		want []*FieldError
+	}{
+		{name: "should return nil for nil error", err: nil, want: nil},
+		{name: "should return nil if tree has no field errors", err: errors.New("failed"), want: nil},
+		{name: "should return single field error", err: price, want: []*FieldError{price}},
+		{
+			name: "should return wrapped field error",
+			err:  fmt.Errorf("binding: %w", price),
+			want: []*FieldError{price},
+		},
+		{
+			name: "should return all joined field errors",
+			err:  fmt.Errorf("binding: %w", errors.Join(price, errors.New("other"), rooms)),
+			want: []*FieldError{price, rooms},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FieldErrors(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	t.Run("should write problem document with problem status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		WriteProblem(w, ValidationProblem(&FieldError{Field: "price", Message: "must be positive"}))
+
+		if got := w.Code; got != http.StatusUnprocessableEntity {
+			t.Errorf("WriteProblem() = status got %d, want %d", got, http.StatusUnprocessableEntity)
+		}
+		if got := w.Header().Get("Content-Type"); got != ContentTypeProblemJSON {
+			t.Errorf("WriteProblem() = content type got %q, want %q", got, ContentTypeProblemJSON)
+		}
+		if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("WriteProblem() = nosniff got %q, want %q", got, "nosniff")
+		}
+		want := `{"detail":"request validation failed","errors":[{"field":"price","message":"must be positive"}],` +
+			`"status":422,"title":"Unprocessable Entity"}`
+		if got := w.Body.String(); got != want {
+			t.Errorf("WriteProblem() = body got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should default to internal server error status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		WriteProblem(w, &Problem{Detail: "unexpected"})
+
+		if got := w.Code; got != http.StatusInternalServerError {
+			t.Errorf("WriteProblem() = status got %d, want %d", got, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("should drop extensions if they cannot be serialized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		WriteProblem(w, NewProblem(http.StatusBadRequest, "invalid").With("nan", math.NaN()))
+
+		want := `{"title":"Bad Request","status":400,"detail":"invalid"}`
+		if got := w.Body.String(); got != want {
+			t.Errorf("WriteProblem() = body got %s, want %s", got, want)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package xhttp
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// DefaultResponder is the Responder used by the package level response helpers such as
+// WriteResponse, OK or Created.
+var DefaultResponder = NewResponder()
+
+// ResponderOption configures the Responder.
+type ResponderOption func(*Responder)
+
+// WithEncoder sets the Encoder used to serialize the response bodies, defaults to the zero
+// value of JSONEncoder. Use JSONEncoder fields to configure the indentation and the
+// HTML escaping of the JSON responses.
+func WithEncoder(enc Encoder) ResponderOption {
+	return func(rs *Responder) {
+		rs.encoder = enc
+	}
+}
+
+// WithDefaultHeader adds the header key with the value to every response written by the
+// Responder, unless the header is already set, e.g. by the handler.
+func WithDefaultHeader(key, value string) ResponderOption {
+	return func(rs *Responder) {
+		rs.headers.Add(key, value)
+	}
+}
+
+// WithEncodeErrorHook sets the function called when the response body fails to serialize,
+// e.g. to log the error or to record a metric, defaults to logging the error using
+// slog.Default. It is called before the HTTP 500 StatusInternalServerError response is written.
+func WithEncodeErrorHook(hook func(err error, body any)) ResponderOption {
+	return func(rs *Responder) {
+		rs.onEncodeError = hook
+	}
+}
+
+// WithEnvelope sets the function that wraps the response bodies before they get serialized,
+// e.g. to return {"data": body}. It is not applied to nil bodies and problem details documents.
+func WithEnvelope(envelope func(code int, body any) any) ResponderOption {
+	return func(rs *Responder) {
+		rs.envelope = envelope
+	}
+}
+
+// Responder writes the HTTP responses using the configured Encoder, default headers and
+// the optional response envelope.
+//
+// Responder is safe for concurrent use once created.
+type Responder struct {
+	encoder       Encoder
+	headers       http.Header
+	onEncodeError func(err error, body any)
+	envelope      func(code int, body any) any
+}
+
+// NewResponder returns a new Responder configured with the supplied options.
+func NewResponder(opts ...ResponderOption) *Responder {
+	rs := &Responder{
+		encoder: JSONEncoder{},
+		headers: make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(rs)
+	}
+	return rs
+}
+
+// WriteResponse replies to the request with the specified body and HTTP code.
+// It does not otherwise end the request; the caller should ensure no further
+// writes are done to w.
+//
+// if body is not nil, it should be a value that can be serialized using the Responder Encoder.
+// A *Problem body is written as is using WriteProblem, its zero status is set to code.
+// If the serialization fails, the request is replied with an HTTP 500 StatusInternalServerError
+// problem details document instead, see WriteProblem. The serialization error is not exposed
+// to the client, see WithEncodeErrorHook.
+//
+// If the handler is wrapped with NegotiateContent middleware, the body is serialized
+// using the negotiated Encoder unless it is of the same media type as the Responder Encoder.
+//
+// Options apply to the single response, e.g. Conditional enables the conditional
+// request handling using the ETag and Last-Modified headers.
+func (rs *Responder) WriteResponse(w http.ResponseWriter, code int, body any, opts ...ResponseOption) {
+	cfg := &responseConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	h := w.Header()
+	for k, v := range rs.headers {
+		if _, ok := h[k]; !ok {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+	if body == nil {
+		if !cfg.writeConditional(w, code, nil) {
+			w.WriteHeader(code)
+		}
+		return
+	}
+	if p, ok := body.(*Problem); ok && p != nil {
+		if p.Status == 0 {
+			problem := *p
+			problem.Status = code
+			p = &problem
+		}
+		WriteProblem(w, p)
+		return
+	}
+	if rs.envelope != nil {
+		body = rs.envelope(code, body)
+	}
+	enc := rs.encoder
+	if negotiated, ok := negotiatedEncoder(w); ok && mediaType(negotiated.ContentType()) != mediaType(enc.ContentType()) {
+		enc = negotiated
+	}
+	b, err := enc.Marshal(body)
+	if err != nil {
+		if rs.onEncodeError != nil {
+			rs.onEncodeError(err, body)
+		} else {
+			slog.Default().Error("failed to serialize response body",
+				slog.String("error", err.Error()),
+				slog.String("type", fmt.Sprintf("%T", body)),
+			)
+		}
+		WriteProblem(w, NewProblem(http.StatusInternalServerError, "failed to serialize response body"))
+		return
+	}
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if cfg.writeConditional(w, code, b) {
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// Created replies to the request with an HTTP 201 StatusCreated and a supplied body (if body was provided).
+func (rs *Responder) Created(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	rs.WriteResponse(w, http.StatusCreated, body, opts...)
+}
+
+// OK replies to the request with an HTTP 200 StatusOK and a supplied body (if body was provided).
+func (rs *Responder) OK(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	rs.WriteResponse(w, http.StatusOK, body, opts...)
+}
+
+// UnprocessableEntity replies to the request with an HTTP 422 StatusUnprocessableEntity and a
+// supplied error body (if body was provided).
+func (rs *Responder) UnprocessableEntity(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	rs.WriteResponse(w, http.StatusUnprocessableEntity, body, opts...)
+}
+
+// BadRequest replies to the request with an HTTP 400 StatusBadRequest and a supplied body (if body was provided).
+func (rs *Responder) BadRequest(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	rs.WriteResponse(w, http.StatusBadRequest, body, opts...)
+}
+
+// InternalServerError replies to the request with an HTTP 500 StatusInternalServerError and a
+// supplied body (if body was provided).
+func (rs *Responder) InternalServerError(w http.ResponseWriter, body any, opts ...ResponseOption) {
+	rs.WriteResponse(w, http.StatusInternalServerError, body, opts...)
+}
@@ -0,0 +1,114 @@
+package xhttp
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponder_WriteResponse(t *testing.T) {
+	t.Run("should use configured encoder", func(t *testing.T) {
+		rs := NewResponder(WithEncoder(JSONEncoder{Indent: "  ", DisableHTMLEscape: true}))
+		w := httptest.NewRecorder()
+		rs.OK(w, map[string]string{"html": "<b>"})
+
+		want := "{\n  \"html\": \"<b>\"\n}"
+		if got := w.Body.String(); got != want {
+			t.Errorf("WriteResponse() = body got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should write default headers", func(t *testing.T) {
+		rs := NewResponder(WithDefaultHeader("Cache-Control", "no-store"))
+		w := httptest.NewRecorder()
+		rs.Created(w, nil)
+
+		if got := w.Code; got != http.StatusCreated {
+			t.Errorf("WriteResponse() = status got %d, want %d", got, http.StatusCreated)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("WriteResponse() = cache control got %q, want %q", got, "no-store")
+		}
+	})
+
+	t.Run("should not override header set by handler", func(t *testing.T) {
+		rs := NewResponder(WithDefaultHeader("Cache-Control", "no-store"))
+		w := httptest.NewRecorder()
+		w.Header().Set("Cache-Control", "max-age=60")
+		rs.OK(w, &dummyResponse{Value: "test"})
+
+		if got := w.Header().Values("Cache-Control"); len(got) != 1 || got[0] != "max-age=60" {
+			t.Errorf("WriteResponse() = cache control got %q, want %q", got, "max-age=60")
+		}
+	})
+
+	t.Run("should wrap body with envelope", func(t *testing.T) {
+		rs := NewResponder(WithEnvelope(func(code int, body any) any {
+			return map[string]any{"code": code, "data": body}
+		}))
+		w := httptest.NewRecorder()
+		rs.UnprocessableEntity(w, &dummyResponse{Value: "test"})
+
+		want := `{"code":422,"data":{"value":"test"}}`
+		if got := w.Body.String(); got != want {
+			t.Errorf("WriteResponse() = body got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should not wrap problem with envelope", func(t *testing.T) {
+		rs := NewResponder(WithEnvelope(func(code int, body any) any {
+			return map[string]any{"code": code, "data": body}
+		}))
+		w := httptest.NewRecorder()
+		rs.WriteResponse(w, http.StatusNotFound, &Problem{Title: "Not Found"})
+
+		if got := w.Code; got != http.StatusNotFound {
+			t.Errorf("WriteResponse() = status got %d, want %d", got, http.StatusNotFound)
+		}
+		if got := w.Header().Get("Content-Type"); got != ContentTypeProblemJSON {
+			t.Errorf("WriteResponse() = content type got %q, want %q", got, ContentTypeProblemJSON)
+		}
+		want := `{"title":"Not Found","status":404}`
+		if got := w.Body.String(); got != want {
+			t.Errorf("WriteResponse() = body got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("should call hook if encoding fails", func(t *testing.T) {
+		var hookErr error
+		rs := NewResponder(WithEncodeErrorHook(func(err error, body any) {
+			hookErr = err
+		}))
+		w := httptest.NewRecorder()
+		rs.BadRequest(w, math.Inf(1))
+
+		if hookErr == nil {
+			t.Errorf("WriteResponse() = expected hook to be called")
+		}
+		if got := w.Code; got != http.StatusInternalServerError {
+			t.Errorf("WriteResponse() = status got %d, want %d", got, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("should prefer configured encoder over negotiated one of the same type", func(t *testing.T) {
+		rs := NewResponder(WithEncoder(JSONEncoder{Indent: " "}))
+		handler := NegotiateContent(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rs.InternalServerError(w, []int{1})
+		}))
+
+		for accept, want := range map[string]string{
+			"application/json": "[\n 1\n]",
+			"application/xml":  "<int>1</int>",
+		} {
+			r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			r.Header.Set("Accept", accept)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got := w.Body.String(); got != want {
+				t.Errorf("WriteResponse() = body for %s got %q, want %q", accept, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryAttempt describes a retry of a request, it is passed to the hook set by WithRetryHook.
+type RetryAttempt struct {
+	// Request is the retried request.
+	Request *http.Request
+	// Attempt is the number of the retry, starting with 1.
+	Attempt int
+	// StatusCode is the status code of the failed attempt, zero if it failed with an error.
+	StatusCode int
+	// Err is the error of the failed attempt, nil if it failed with a retryable status code.
+	Err error
+	// Delay is the time waited before the retry.
+	Delay time.Duration
+}
+
+// RetryBudget limits the number of the retries to a ratio of the requests, so that the
+// retries cannot multiply the load of a struggling service, i.e. cause a retry storm.
+//
+// Every request deposits ratio tokens and every retry withdraws one token. On top of that,
+// minPerSecond tokens are deposited every second, so the low traffic clients can retry
+// as well. The unused budget is capped at the tokens of 100 requests plus 10 seconds of the
+// minimum rate.
+//
+// RetryBudget can be shared by multiple transports, e.g. to limit the retries to a single
+// service. It is safe for concurrent use.
+type RetryBudget struct {
+	ratio float64
+	rate  float64
+	max   float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget returns a new RetryBudget allowing the retries of ratio of the requests,
+// e.g. 0.2 for 20%, plus minPerSecond retries per second.
+func NewRetryBudget(ratio float64, minPerSecond int) *RetryBudget {
+	b := &RetryBudget{
+		ratio: ratio,
+		rate:  float64(minPerSecond),
+		max:   ratio*100 + float64(minPerSecond)*10,
+		now:   time.Now,
+	}
+	b.tokens = b.max
+	return b
+}
+
+// deposit adds the tokens of a request. The nil budget is unlimited.
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = math.Min(b.max, b.tokens+b.ratio)
+}
+
+// withdraw takes the token of a retry, it reports false if the budget is exhausted.
+// The nil budget is unlimited.
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens of the minimum rate, it must be called with mu held.
+func (b *RetryBudget) refill() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.max, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// RetryOption configures RetryTransport.
+type RetryOption func(*RetryTransport)
+
+// WithMaxRetries sets the maximum number of the retries of a request, defaults to 3.
+func WithMaxRetries(n int) RetryOption {
+	return func(t *RetryTransport) {
+		t.maxRetries = n
+	}
+}
+
+// WithBackoff sets the base and the maximum delay of the exponential backoff, default to
+// 100ms and 5s. The delay before the nth retry is random between zero and base * 2^(n-1),
+// capped at max, i.e. the full jitter backoff.
+func WithBackoff(base, max time.Duration) RetryOption {
+	return func(t *RetryTransport) {
+		t.baseDelay = base
+		t.maxDelay = max
+	}
+}
+
+// WithRetryBudget sets the RetryBudget of the transport, defaults to a budget of 20% of the
+// requests plus 10 retries per second. A nil budget disables the limit, the retries are
+// then limited by WithMaxRetries only.
+func WithRetryBudget(b *RetryBudget) RetryOption {
+	return func(t *RetryTransport) {
+		t.budget = b
+	}
+}
+
+// WithRetryStatus sets the status codes of the responses that are retried, defaults to HTTP
+// 429 StatusTooManyRequests, 502 StatusBadGateway, 503 StatusServiceUnavailable and 504
+// StatusGatewayTimeout.
+func WithRetryStatus(codes ...int) RetryOption {
+	return func(t *RetryTransport) {
+		t.statuses = codes
+	}
+}
+
+// WithRetryHook sets the function called before every retry, e.g. to record a metric.
+func WithRetryHook(hook func(RetryAttempt)) RetryOption {
+	return func(t *RetryTransport) {
+		t.hook = hook
+	}
+}
+
+// RetryTransport is an http.RoundTripper retrying the requests that failed with an error,
+// e.g. a connection reset, or with a retryable status code.
+//
+// Only the requests that are safe to retry are retried: the ones with an idempotent method,
+// i.e. GET, HEAD, OPTIONS, TRACE, PUT and DELETE, and the ones with the Idempotency-Key
+// header. The request bodies are rewound using Request.GetBody, the requests with a body
+// and without GetBody are not retried.
+//
+// The retries are delayed using the exponential backoff with full jitter, see WithBackoff.
+// The Retry-After header of the response takes precedence over the backoff, and if it
+// exceeds the maximum delay, the response is returned without retrying. The retries stop
+// when the request context is done or the RetryBudget is exhausted. The *CircuitOpenError
+// of BreakerTransport is not retried.
+type RetryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	budget     *RetryBudget
+	statuses   []int
+	hook       func(RetryAttempt)
+}
+
+// NewRetryTransport returns a new RetryTransport sending the requests using next,
+// http.DefaultTransport if nil.
+func NewRetryTransport(next http.RoundTripper, opts ...RetryOption) *RetryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &RetryTransport{
+		next:       next,
+		maxRetries: 3,
+		baseDelay:  100 * time.Millisecond,
+		maxDelay:   5 * time.Second,
+		budget:     NewRetryBudget(0.2, 10),
+		statuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.deposit()
+	retryable := retryableRequest(req)
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		res, err := t.next.RoundTrip(r)
+		if !retryable || attempt >= t.maxRetries || !t.shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		delay := t.backoff(attempt)
+		if res != nil {
+			if d, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				if d > t.maxDelay {
+					return res, err
+				}
+				delay = d
+			}
+		}
+		if !t.budget.withdraw() {
+			return res, err
+		}
+		if res != nil {
+			// the connection can be reused only if the body is read to the end
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			_ = res.Body.Close()
+		}
+		if t.hook != nil {
+			a := RetryAttempt{Request: req, Attempt: attempt + 1, Err: err, Delay: delay}
+			if res != nil {
+				a.StatusCode = res.StatusCode
+			}
+			t.hook(a)
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether the attempt failed in a retryable way.
+func (t *RetryTransport) shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		var coe *CircuitOpenError
+		return !errors.As(err, &coe)
+	}
+	return slices.Contains(t.statuses, res.StatusCode)
+}
+
+// backoff returns the full jitter delay before the retry following the attempt.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	ceiling := t.maxDelay
+	if attempt < 32 {
+		ceiling = min(ceiling, t.baseDelay<<attempt)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryableRequest reports whether the request is safe to retry.
+func retryableRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter parses the Retry-After header value, either the delay in seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done, in which case it returns the context error.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
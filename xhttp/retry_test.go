@@ -0,0 +1,263 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// responses returns a RoundTripper replying with the supplied status codes in order, zero
+// codes result in a connection reset error. The bodies of the requests are recorded.
+func responses(bodies *[]string, codes ...int) http.RoundTripper {
+	i := 0
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Body != nil {
+			b, _ := io.ReadAll(r.Body)
+			*bodies = append(*bodies, string(b))
+		}
+		code := codes[min(i, len(codes)-1)]
+		i++
+		if code == 0 {
+			return nil, syscall.ECONNRESET
+		}
+		res := &http.Response{StatusCode: code, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: r}
+		if code == http.StatusTooManyRequests {
+			res.Header.Set("Retry-After", "120")
+		}
+		return res, nil
+	})
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		header       http.Header
+		body         io.Reader
+		codes        []int
+		wantStatus   int
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "should retry get until success",
+			method:       http.MethodGet,
+			codes:        []int{http.StatusServiceUnavailable, 0, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name:         "should stop after max retries",
+			method:       http.MethodGet,
+			codes:        []int{http.StatusBadGateway},
+			wantStatus:   http.StatusBadGateway,
+			wantAttempts: 4,
+		},
+		{
+			name:         "should return error after max retries",
+			method:       http.MethodDelete,
+			codes:        []int{0},
+			wantErr:      syscall.ECONNRESET,
+			wantAttempts: 4,
+		},
+		{
+			name:         "should not retry not retryable status",
+			method:       http.MethodGet,
+			codes:        []int{http.StatusInternalServerError},
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "should not retry post",
+			method:       http.MethodPost,
+			body:         strings.NewReader(`{"title":"flat"}`),
+			codes:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		{
+			name:         "should retry post with idempotency key and rewind body",
+			method:       http.MethodPost,
+			header:       http.Header{"Idempotency-Key": {"k1"}},
+			body:         strings.NewReader(`{"title":"flat"}`),
+			codes:        []int{http.StatusServiceUnavailable, http.StatusCreated},
+			wantStatus:   http.StatusCreated,
+			wantAttempts: 2,
+		},
+		{
+			name:         "should not retry body without get body",
+			method:       http.MethodPut,
+			body:         io.MultiReader(strings.NewReader(`{"title":"flat"}`)),
+			codes:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		{
+			name:         "should not retry if retry after exceeds max delay",
+			method:       http.MethodGet,
+			codes:        []int{http.StatusTooManyRequests, http.StatusOK},
+			wantStatus:   http.StatusTooManyRequests,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			var attempts []RetryAttempt
+			rt := NewRetryTransport(responses(&bodies, tt.codes...),
+				WithBackoff(0, time.Second),
+				WithRetryHook(func(a RetryAttempt) { attempts = append(attempts, a) }),
+			)
+			req, _ := http.NewRequest(tt.method, "http://listings.internal/listings", tt.body)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			res, err := rt.RoundTrip(req)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RoundTrip() error = %v, want %v", err, tt.wantErr)
+			}
+			if res != nil && res.StatusCode != tt.wantStatus {
+				t.Errorf("RoundTrip() = status got %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := len(attempts) + 1; got != tt.wantAttempts {
+				t.Errorf("RoundTrip() = attempts got %d, want %d", got, tt.wantAttempts)
+			}
+			for i, a := range attempts {
+				if a.Attempt != i+1 {
+					t.Errorf("RoundTrip() = retry attempt got %d, want %d", a.Attempt, i+1)
+				}
+			}
+			if tt.body != nil && len(bodies) == tt.wantAttempts {
+				for _, b := range bodies {
+					if b != `{"title":"flat"}` {
+						t.Errorf("RoundTrip() = request body got %s, want %s", b, `{"title":"flat"}`)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	var delays []time.Duration
+	i := 0
+	rt := NewRetryTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		i++
+		res := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}
+		if i == 1 {
+			res.StatusCode = http.StatusServiceUnavailable
+			res.Header.Set("Retry-After", "0")
+		}
+		return res, nil
+	}), WithBackoff(time.Hour, time.Hour), WithRetryHook(func(a RetryAttempt) { delays = append(delays, a.Delay) }))
+
+	req := httptest.NewRequest(http.MethodGet, "http://listings.internal/listings", nil)
+	res, err := rt.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("RoundTrip() = got %v, %v, want %d", res, err, http.StatusOK)
+	}
+	if len(delays) != 1 || delays[0] != 0 {
+		t.Errorf("RoundTrip() = delays got %v, want [0s]", delays)
+	}
+}
+
+func TestRetryTransport_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var bodies []string
+	rt := NewRetryTransport(responses(&bodies, http.StatusServiceUnavailable), WithBackoff(time.Hour, time.Hour))
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://listings.internal/listings", nil)
+	_, err := rt.RoundTrip(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	b := NewRetryBudget(0.5, 1)
+	b.now = func() time.Time { return now }
+	b.tokens = 0
+
+	if b.withdraw() {
+		t.Errorf("withdraw() = got true for empty budget, want false")
+	}
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Errorf("withdraw() = got false after two requests, want true")
+	}
+	if b.withdraw() {
+		t.Errorf("withdraw() = got true for empty budget, want false")
+	}
+	now = now.Add(time.Second)
+	if !b.withdraw() {
+		t.Errorf("withdraw() = got false after a second, want true")
+	}
+}
+
+func TestRetryTransport_Budget(t *testing.T) {
+	var bodies []string
+	budget := NewRetryBudget(0.01, 0) // a single retry
+	rt := NewRetryTransport(responses(&bodies, http.StatusServiceUnavailable), WithBackoff(0, 0), WithRetryBudget(budget))
+
+	res, _ := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://listings.internal/listings", nil))
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("RoundTrip() = status got %d, want %d", res.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := len(bodies); got != 2 {
+		t.Errorf("RoundTrip() = attempts got %d, want 2", got)
+	}
+}
+
+func TestRetryTransport_NilBudget(t *testing.T) {
+	var bodies []string
+	rt := NewRetryTransport(responses(&bodies, http.StatusServiceUnavailable), WithBackoff(0, 0), WithRetryBudget(nil))
+
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://listings.internal/listings", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("RoundTrip() = status got %d, want %d", res.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := len(bodies); got != 4 {
+		t.Errorf("RoundTrip() = attempts got %d, want 4", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", want: 0, wantOK: false},
+		{value: "30", want: 30 * time.Second, wantOK: true},
+		{value: "Mon, 01 May 2023 00:01:00 GMT", want: time.Minute, wantOK: true},
+		{value: "Sun, 30 Apr 2023 00:00:00 GMT", want: 0, wantOK: true},
+		{value: "soon", want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run("should parse "+tt.value, func(t *testing.T) {
+			got, ok := retryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter() = got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
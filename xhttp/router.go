@@ -0,0 +1,300 @@
+package xhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Router is an http.Handler routing the requests by the method and the path pattern.
+//
+// A pattern consists of the segments separated by slashes. A segment is either static, e.g.
+// "listings", a path parameter matching a single segment, e.g. "{id}", or a wildcard matching
+// the rest of the path including the slashes, e.g. "{path...}", which must be the last one.
+// Static segments take precedence over the parameters, which take precedence over the
+// wildcards, regardless of the order of the registration, e.g. "/listings/new" wins over
+// "/listings/{id}". A less specific route is matched if the more specific one has no handler
+// of the request method, e.g. POST "/listings/new" is routed to POST "/listings/{id}".
+//
+// The values of the path parameters are read using PathParam, and the pattern of the
+// matched route using RoutePattern. Handle reads the path parameters of the Router by default.
+//
+// Requests not matching any route are replied with an HTTP 404 StatusNotFound problem details
+// document, and the requests matching only routes with different methods with an HTTP 405
+// StatusMethodNotAllowed one and the Allow header listing the methods of all the matching
+// routes. The routes registered for GET handle HEAD requests as well.
+//
+// Router is safe for concurrent use once all the routes are registered.
+type Router struct {
+	root       *routeNode
+	prefix     string
+	middleware []func(http.Handler) http.Handler
+}
+
+// NewRouter returns a new Router without any routes.
+func NewRouter() *Router {
+	return &Router{root: &routeNode{}}
+}
+
+// Use appends the middleware applied to the routes registered afterwards. The middleware
+// are not applied to the 404 and 405 responses, wrap the Router itself to apply them to all
+// the requests.
+func (rt *Router) Use(middleware ...func(http.Handler) http.Handler) {
+	rt.middleware = append(rt.middleware, middleware...)
+}
+
+// Group returns a Router registering the routes under the prefix into rt, e.g. "/api/v1",
+// with the middleware of rt followed by the supplied middleware.
+func (rt *Router) Group(prefix string, middleware ...func(http.Handler) http.Handler) *Router {
+	return &Router{
+		root:       rt.root,
+		prefix:     rt.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: append(slices.Clip(rt.middleware), middleware...),
+	}
+}
+
+// Prefix returns the prefix of the patterns of the routes registered by rt, see Group.
+func (rt *Router) Prefix() string {
+	return rt.prefix
+}
+
+// Handle registers the handler for the method and the pattern. It panics if the pattern is
+// invalid or the route is already registered.
+func (rt *Router) Handle(method, pattern string, h http.Handler) {
+	pattern = rt.prefix + pattern
+	if !strings.HasPrefix(pattern, "/") {
+		panic(fmt.Sprintf("xhttp: pattern %q must start with a slash", pattern))
+	}
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+
+	n := rt.root
+	segments := strings.Split(pattern[1:], "/")
+	for i, s := range segments {
+		name, isParam := strings.CutPrefix(s, "{")
+		if !isParam {
+			n = n.staticChild(s)
+			continue
+		}
+		name, ok := strings.CutSuffix(name, "}")
+		if !ok || name == "" {
+			panic(fmt.Sprintf("xhttp: invalid segment %q of pattern %q", s, pattern))
+		}
+		if name, ok = strings.CutSuffix(name, "..."); ok {
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("xhttp: wildcard %q of pattern %q must be the last segment", s, pattern))
+			}
+			n = n.paramChild(&n.wildcard, name, pattern)
+			break
+		}
+		n = n.paramChild(&n.param, name, pattern)
+	}
+
+	if n.routes == nil {
+		n.routes = make(map[string]*route)
+	}
+	if _, ok := n.routes[method]; ok {
+		panic(fmt.Sprintf("xhttp: route %s %s is already registered", method, pattern))
+	}
+	n.routes[method] = &route{pattern: pattern, handler: h}
+}
+
+// HandleFunc registers the handler function for the method and the pattern, see Handle.
+func (rt *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+// Get registers the handler for GET requests to the pattern, see Handle.
+func (rt *Router) Get(pattern string, h http.Handler) { rt.Handle(http.MethodGet, pattern, h) }
+
+// Post registers the handler for POST requests to the pattern, see Handle.
+func (rt *Router) Post(pattern string, h http.Handler) { rt.Handle(http.MethodPost, pattern, h) }
+
+// Put registers the handler for PUT requests to the pattern, see Handle.
+func (rt *Router) Put(pattern string, h http.Handler) { rt.Handle(http.MethodPut, pattern, h) }
+
+// Patch registers the handler for PATCH requests to the pattern, see Handle.
+func (rt *Router) Patch(pattern string, h http.Handler) { rt.Handle(http.MethodPatch, pattern, h) }
+
+// Delete registers the handler for DELETE requests to the pattern, see Handle.
+func (rt *Router) Delete(pattern string, h http.Handler) { rt.Handle(http.MethodDelete, pattern, h) }
+
+// ServeHTTP implements http.Handler interface.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	var params []routeParam
+	allowed := make(map[string]bool)
+	rte := rt.root.match(segments, r.Method, &params, allowed)
+	if rte == nil && len(allowed) == 0 {
+		WriteError(w, r, ErrNotFound("route not found"))
+		return
+	}
+	if rte == nil {
+		methods := make([]string, 0, len(allowed))
+		for method := range allowed {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		WriteError(w, r, NewStatusError(http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method), nil))
+		return
+	}
+
+	m, ok := r.Context().Value(routeMatchKey{}).(*routeMatch)
+	if !ok {
+		m = &routeMatch{}
+		r = r.WithContext(context.WithValue(r.Context(), routeMatchKey{}, m))
+	}
+	m.pattern = rte.pattern
+	m.params = params
+	rte.handler.ServeHTTP(w, r)
+}
+
+type routeMatchKey struct{}
+
+// routeMatch is the route matched by Router stored in the request context.
+type routeMatch struct {
+	pattern string
+	params  []routeParam
+}
+
+type routeParam struct {
+	name  string
+	value string
+}
+
+// ContextWithRoute returns a copy of ctx that records the route matched by Router, so that
+// RoutePattern can read it in the middleware wrapping the Router, e.g. the access log or
+// the metrics. Without it, the route can be read only in the handlers of the Router.
+func ContextWithRoute(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routeMatchKey{}).(*routeMatch); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routeMatchKey{}, &routeMatch{})
+}
+
+// RoutePattern returns the pattern of the route of the request matched by Router, e.g.
+// "/api/v1/listings/{id}", or an empty string if no route has been matched, see ContextWithRoute.
+func RoutePattern(r *http.Request) string {
+	if m, ok := r.Context().Value(routeMatchKey{}).(*routeMatch); ok {
+		return m.pattern
+	}
+	return ""
+}
+
+// PathParam returns the value of the path parameter name of the request matched by Router,
+// or an empty string if the parameter is not present. It implements PathParamFunc.
+func PathParam(r *http.Request, name string) string {
+	if m, ok := r.Context().Value(routeMatchKey{}).(*routeMatch); ok {
+		for _, p := range m.params {
+			if p.name == name {
+				return p.value
+			}
+		}
+	}
+	return ""
+}
+
+var _ PathParamFunc = PathParam
+
+// route is a handler registered for a method and a pattern.
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// routeNode is a node of the tree of the path segments.
+type routeNode struct {
+	static   map[string]*routeNode
+	param    *routeNode
+	wildcard *routeNode
+	name     string // of the parameter or the wildcard
+	routes   map[string]*route
+}
+
+// staticChild returns the child of the static segment s, adding it if necessary.
+func (n *routeNode) staticChild(s string) *routeNode {
+	if n.static == nil {
+		n.static = make(map[string]*routeNode)
+	}
+	child, ok := n.static[s]
+	if !ok {
+		child = &routeNode{}
+		n.static[s] = child
+	}
+	return child
+}
+
+// paramChild returns the parameter or the wildcard child, adding it if necessary. All the
+// patterns must use the same name for the parameter at the same position.
+func (n *routeNode) paramChild(child **routeNode, name, pattern string) *routeNode {
+	if *child == nil {
+		*child = &routeNode{name: name}
+	}
+	if (*child).name != name {
+		panic(fmt.Sprintf("xhttp: parameter %q of pattern %q conflicts with parameter %q of the registered routes", name, pattern, (*child).name))
+	}
+	return *child
+}
+
+// match returns the route of the method of the node matching the escaped path segments and
+// appends the values of the parameters to params. The static segments take precedence over
+// the parameters and the parameters over the wildcards, unless the more specific node has
+// no route of the method. The methods of the routes of all the matching nodes are added to
+// allowed, so that a nil route with empty allowed means no node matches.
+func (n *routeNode) match(segments []string, method string, params *[]routeParam, allowed map[string]bool) *route {
+	if len(segments) == 0 {
+		return n.route(method, allowed)
+	}
+	s, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return nil
+	}
+	if child, ok := n.static[s]; ok {
+		if rte := child.match(segments[1:], method, params, allowed); rte != nil {
+			return rte
+		}
+	}
+	if n.param != nil && s != "" {
+		l := len(*params)
+		*params = append(*params, routeParam{name: n.param.name, value: s})
+		if rte := n.param.match(segments[1:], method, params, allowed); rte != nil {
+			return rte
+		}
+		*params = (*params)[:l]
+	}
+	if n.wildcard != nil {
+		rest, err := url.PathUnescape(strings.Join(segments, "/"))
+		if err != nil {
+			return nil
+		}
+		if rte := n.wildcard.route(method, allowed); rte != nil {
+			*params = append(*params, routeParam{name: n.wildcard.name, value: rest})
+			return rte
+		}
+	}
+	return nil
+}
+
+// route returns the route of the method, HEAD requests fall back to the GET route. If the
+// node has no route of the method, the methods of its routes are added to allowed.
+func (n *routeNode) route(method string, allowed map[string]bool) *route {
+	rte, ok := n.routes[method]
+	if !ok && method == http.MethodHead {
+		rte, ok = n.routes[http.MethodGet]
+	}
+	if ok {
+		return rte
+	}
+	for m := range n.routes {
+		allowed[m] = true
+	}
+	if _, ok := n.routes[http.MethodGet]; ok {
+		allowed[http.MethodHead] = true
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package xhttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoRoute returns a handler writing the route pattern and the supplied path parameters.
+func echoRoute(params ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values := []string{RoutePattern(r)}
+		for _, name := range params {
+			values = append(values, name+"="+PathParam(r, name))
+		}
+		_, _ = w.Write([]byte(strings.Join(values, " ")))
+	})
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	router.Get("/listings", echoRoute())
+	router.Post("/listings", echoRoute())
+	router.Get("/listings/new", echoRoute())
+	router.Get("/listings/{id}", echoRoute("id"))
+	router.Delete("/listings/{id}", echoRoute("id"))
+	router.Post("/listings/{id}", echoRoute("id"))
+	router.Get("/listings/{id}/photos/{photo}", echoRoute("id", "photo"))
+	router.Get("/files/{path...}", echoRoute("path"))
+	router.HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("root"))
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{
+			name:       "should route static pattern",
+			method:     http.MethodGet,
+			target:     "/listings",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings",
+		},
+		{
+			name:       "should route by method",
+			method:     http.MethodPost,
+			target:     "/listings",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings",
+		},
+		{
+			name:       "should route root",
+			method:     http.MethodGet,
+			target:     "/",
+			wantStatus: http.StatusOK,
+			wantBody:   "root",
+		},
+		{
+			name:       "should prefer static segment over parameter",
+			method:     http.MethodGet,
+			target:     "/listings/new",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings/new",
+		},
+		{
+			name:       "should route path parameters",
+			method:     http.MethodGet,
+			target:     "/listings/123/photos/a%2Fb",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings/{id}/photos/{photo} id=123 photo=a/b",
+		},
+		{
+			name:       "should route wildcard",
+			method:     http.MethodGet,
+			target:     "/files/2023/05/plan.pdf",
+			wantStatus: http.StatusOK,
+			wantBody:   "/files/{path...} path=2023/05/plan.pdf",
+		},
+		{
+			name:       "should route head to get",
+			method:     http.MethodHead,
+			target:     "/listings/123",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings/{id} id=123",
+		},
+		{
+			name:       "should reply not found to unknown path",
+			method:     http.MethodGet,
+			target:     "/listings/123/videos",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"title":"Not Found","status":404,"detail":"route not found","instance":"/listings/123/videos"}`,
+		},
+		{
+			name:       "should reply not found to empty parameter",
+			method:     http.MethodGet,
+			target:     "/listings/123/photos/",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"title":"Not Found","status":404,"detail":"route not found","instance":"/listings/123/photos/"}`,
+		},
+		{
+			name:       "should reply method not allowed with allow header",
+			method:     http.MethodPut,
+			target:     "/listings/123",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"title":"Method Not Allowed","status":405,"detail":"method PUT is not allowed","instance":"/listings/123"}`,
+			wantAllow:  "DELETE, GET, HEAD, POST",
+		},
+		{
+			name:       "should fall back to parameter if static segment has no route of method",
+			method:     http.MethodPost,
+			target:     "/listings/new",
+			wantStatus: http.StatusOK,
+			wantBody:   "/listings/{id} id=new",
+		},
+		{
+			name:       "should reply method not allowed with methods of all matching routes",
+			method:     http.MethodPut,
+			target:     "/listings/new",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"title":"Method Not Allowed","status":405,"detail":"method PUT is not allowed","instance":"/listings/new"}`,
+			wantAllow:  "DELETE, GET, HEAD, POST",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if got := w.Code; got != tt.wantStatus {
+				t.Errorf("ServeHTTP() = status got %d, want %d", got, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("ServeHTTP() = body got %s, want %s", got, tt.wantBody)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("ServeHTTP() = allow header got %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestRouter_Group(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	router := NewRouter()
+	router.Use(middleware("root"))
+	api := router.Group("/api/v1/", middleware("api"))
+	api.Get("/listings/{id}", echoRoute("id"))
+	admin := api.Group("/admin")
+	admin.Use(middleware("admin"))
+	admin.Delete("/listings/{id}", echoRoute("id"))
+	router.Get("/health", echoRoute())
+
+	tests := []struct {
+		method    string
+		target    string
+		wantBody  string
+		wantCalls string
+	}{
+		{method: http.MethodGet, target: "/api/v1/listings/1", wantBody: "/api/v1/listings/{id} id=1", wantCalls: "root api"},
+		{method: http.MethodDelete, target: "/api/v1/admin/listings/1", wantBody: "/api/v1/admin/listings/{id} id=1", wantCalls: "root api admin"},
+		{method: http.MethodGet, target: "/health", wantBody: "/health", wantCalls: "root"},
+		{method: http.MethodGet, target: "/api/v1/other", wantCalls: ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("should route %s %s", tt.method, tt.target), func(t *testing.T) {
+			calls = nil
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if got := w.Body.String(); tt.wantBody != "" && got != tt.wantBody {
+				t.Errorf("ServeHTTP() = body got %s, want %s", got, tt.wantBody)
+			}
+			if got := strings.Join(calls, " "); got != tt.wantCalls {
+				t.Errorf("ServeHTTP() = middleware got %q, want %q", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	type getListing struct {
+		ID int `path:"id"`
+	}
+	router := NewRouter()
+	router.Get("/listings/{id}", Handle(func(ctx context.Context, req getListing) (getListing, error) {
+		return req, nil
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/listings/123", nil))
+	if got, want := w.Body.String(), `{"ID":123}`; got != want {
+		t.Errorf("ServeHTTP() = body got %s, want %s", got, want)
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	router := NewRouter()
+	router.Get("/listings/{id}", echoRoute())
+	var got string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(ContextWithRoute(r.Context()))
+		router.ServeHTTP(w, r)
+		got = RoutePattern(r)
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/listings/1", nil))
+
+	if want := "/listings/{id}"; got != want {
+		t.Errorf("RoutePattern() = got %q, want %q", got, want)
+	}
+	if got := RoutePattern(httptest.NewRequest(http.MethodGet, "/listings/1", nil)); got != "" {
+		t.Errorf("RoutePattern() = got %q for request without route, want empty", got)
+	}
+}
+
+func TestRouter_Panics(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{name: "should panic for pattern without slash", pattern: "listings"},
+		{name: "should panic for unclosed parameter", pattern: "/listings/{id"},
+		{name: "should panic for wildcard not at the end", pattern: "/files/{path...}/meta"},
+		{name: "should panic for conflicting parameter name", pattern: "/listings/{slug}"},
+		{name: "should panic for duplicate route", pattern: "/listings/{id}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			router.Get("/listings/{id}", echoRoute())
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Handle() = expected panic for pattern %q", tt.pattern)
+				}
+			}()
+			router.Get(tt.pattern, echoRoute())
+		})
+	}
+}
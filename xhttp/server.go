@@ -0,0 +1,176 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ReadinessFlag is implemented by the readiness probes that can be switched to not ready,
+// e.g. *health.Registry.
+type ReadinessFlag interface {
+	SetReady(ready bool)
+}
+
+// ShutdownHook is a function run by Run after the server is shut down, e.g. to close the
+// database connections. The context expires with the shutdown timeout.
+type ShutdownHook func(ctx context.Context) error
+
+// RunOption configures Run.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	readiness       ReadinessFlag
+	hooks           []ShutdownHook
+	listener        net.Listener
+	signals         []os.Signal
+}
+
+// WithShutdownTimeout sets the time given to the in-flight requests and the shutdown hooks
+// to complete, defaults to 30s.
+func WithShutdownTimeout(d time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithReadiness sets the readiness flag that is set to ready once the server is listening
+// and to not ready as soon as the shutdown begins.
+func WithReadiness(flag ReadinessFlag) RunOption {
+	return func(c *runConfig) {
+		c.readiness = flag
+	}
+}
+
+// WithDrainDelay sets the time between switching the readiness to not ready and the
+// shutdown of the server, during which the server keeps accepting new requests, so that
+// the load balancers notice the readiness change. Defaults to no delay.
+func WithDrainDelay(d time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.drainDelay = d
+	}
+}
+
+// WithShutdownHook adds the hook run after the server is shut down. Hooks are run in the
+// order they were added.
+func WithShutdownHook(hook ShutdownHook) RunOption {
+	return func(c *runConfig) {
+		c.hooks = append(c.hooks, hook)
+	}
+}
+
+// WithListener sets the listener the server accepts the connections on, instead of
+// listening on the server Addr.
+func WithListener(l net.Listener) RunOption {
+	return func(c *runConfig) {
+		c.listener = l
+	}
+}
+
+// WithSignals sets the signals that stop the server, defaults to SIGINT and SIGTERM.
+// Without any signals, the signal handling is disabled and the server is stopped only by
+// cancelling the context of Run, e.g. when the signals are handled by the application.
+func WithSignals(signals ...os.Signal) RunOption {
+	return func(c *runConfig) {
+		c.signals = signals
+	}
+}
+
+// Run listens on the server Addr and serves the requests until ctx is done or one of the
+// signals (SIGINT and SIGTERM by default) is received. The server is served with TLS if
+// its TLSConfig has certificates.
+//
+// On stop, Run sets the readiness flag to not ready, waits for the drain delay and shuts
+// the server down gracefully, waiting for the in-flight requests to complete within the
+// shutdown timeout. Connections still active after the timeout are closed. Finally, the
+// shutdown hooks are run in order. A second signal received during the shutdown terminates
+// the process immediately.
+//
+// Run returns nil after a graceful shutdown, otherwise the errors of the server, the shutdown
+// and the hooks joined using errors.Join.
+func Run(ctx context.Context, srv *http.Server, opts ...RunOption) error {
+	cfg := &runConfig{
+		shutdownTimeout: 30 * time.Second,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	stop := func() {}
+	if len(cfg.signals) > 0 {
+		ctx, stop = signal.NotifyContext(ctx, cfg.signals...)
+		defer stop()
+	}
+
+	ln := cfg.listener
+	if ln == nil {
+		addr := srv.Addr
+		if addr == "" {
+			addr = ":http"
+			if srv.TLSConfig != nil {
+				addr = ":https"
+			}
+		}
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return fmt.Errorf("listening on %s: %w", addr, err)
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil && (len(srv.TLSConfig.Certificates) > 0 || srv.TLSConfig.GetCertificate != nil) {
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		serveErr <- srv.Serve(ln)
+	}()
+	if cfg.readiness != nil {
+		cfg.readiness.SetReady(true)
+	}
+
+	var errs []error
+	failed := false
+	select {
+	case err := <-serveErr:
+		// the server failed on its own, there is nothing to drain
+		errs = append(errs, fmt.Errorf("serving: %w", err))
+		failed = true
+	case <-ctx.Done():
+		// restore the default behavior, so that the second signal terminates the process
+		stop()
+	}
+	if cfg.readiness != nil {
+		cfg.readiness.SetReady(false)
+	}
+	if !failed && cfg.drainDelay > 0 {
+		time.Sleep(cfg.drainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("shutting down: %w", err))
+		_ = srv.Close()
+	}
+	if !failed {
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("serving: %w", err))
+		}
+	}
+	for i, hook := range cfg.hooks {
+		if err := hook(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("running shutdown hook %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
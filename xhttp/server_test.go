@@ -0,0 +1,145 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type readinessRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *readinessRecorder) SetReady(ready bool) {
+	r.record("ready=" + map[bool]string{true: "true", false: "false"}[ready])
+}
+
+func (r *readinessRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	return ln
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should drain in-flight requests and run hooks in order", func(t *testing.T) {
+		ln := listen(t)
+		started := make(chan struct{})
+		shuttingDown := make(chan struct{})
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-shuttingDown
+			OK(w, "done")
+		})}
+		srv.RegisterOnShutdown(func() { close(shuttingDown) })
+		recorder := &readinessRecorder{}
+		hook := func(name string) ShutdownHook {
+			return func(ctx context.Context) error {
+				recorder.record(name)
+				return nil
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- Run(ctx, srv,
+				WithListener(ln),
+				WithReadiness(recorder),
+				WithShutdownHook(hook("close db")),
+				WithShutdownHook(hook("flush metrics")),
+			)
+		}()
+
+		respBody := make(chan string, 1)
+		go func() {
+			res, err := http.Get("http://" + ln.Addr().String())
+			if err != nil {
+				respBody <- err.Error()
+				return
+			}
+			defer res.Body.Close()
+			b, _ := io.ReadAll(res.Body)
+			respBody <- string(b)
+		}()
+		<-started
+		cancel()
+
+		if err := <-runErr; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+		if got := <-respBody; got != `"done"` {
+			t.Errorf("Run() = in-flight response got %s, want %s", got, `"done"`)
+		}
+		want := []string{"ready=true", "ready=false", "close db", "flush metrics"}
+		if !reflect.DeepEqual(recorder.events, want) {
+			t.Errorf("Run() = events got %v, want %v", recorder.events, want)
+		}
+	})
+
+	t.Run("should close connections after shutdown timeout", func(t *testing.T) {
+		ln := listen(t)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- Run(ctx, srv, WithListener(ln), WithShutdownTimeout(50*time.Millisecond))
+		}()
+		go func() {
+			res, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				res.Body.Close()
+			}
+		}()
+		<-started
+		cancel()
+
+		if err := <-runErr; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("should return listen error", func(t *testing.T) {
+		ln := listen(t)
+		defer ln.Close()
+
+		err := Run(context.Background(), &http.Server{Addr: ln.Addr().String()})
+		if err == nil {
+			t.Errorf("Run() = expected error for address in use")
+		}
+	})
+
+	t.Run("should return hook errors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := Run(ctx, &http.Server{}, WithListener(listen(t)), WithShutdownHook(func(ctx context.Context) error {
+			return errors.New("closing db: timeout")
+		}))
+		if want := "running shutdown hook 0: closing db: timeout"; err == nil || err.Error() != want {
+			t.Errorf("Run() error = %v, want %v", err, want)
+		}
+	})
+}
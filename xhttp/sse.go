@@ -0,0 +1,210 @@
+package xhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentTypeEventStream is the media type of the Server-Sent Events streams.
+const ContentTypeEventStream = "text/event-stream"
+
+var errSSEClosed = errors.New("sse writer is closed")
+
+// Event is a single Server-Sent Event.
+type Event struct {
+	// ID sets the event stream's last event ID value, see LastEventID.
+	ID string
+	// Name is the event type, if empty the client dispatches a "message" event.
+	Name string
+	// Retry is the reconnection time the client should use, it is sent if positive.
+	Retry time.Duration
+	// Data is the event payload. Strings and byte slices are sent as is, any other value
+	// is serialized using JSONEncoder. Multi-line payloads are sent as multiple data lines.
+	Data any
+}
+
+// SSEOption configures the SSE writer.
+type SSEOption func(*sseConfig)
+
+type sseConfig struct {
+	heartbeat time.Duration
+}
+
+// Heartbeat sets the interval in which the comment lines are sent to the client to keep
+// the connection open through proxies. Heartbeats are disabled by default.
+func Heartbeat(d time.Duration) SSEOption {
+	return func(c *sseConfig) {
+		c.heartbeat = d
+	}
+}
+
+// SSE writes Server-Sent Events to the client.
+//
+// SSE is safe for concurrent use. It stops accepting events once the client disconnects,
+// i.e. when the request context is done.
+type SSE struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+
+	mu     sync.Mutex
+	closed bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSSE replies to the request with an HTTP 200 StatusOK text/event-stream response and
+// returns the SSE writer for the events. It returns an error if w does not support flushing.
+//
+// Close must be called before the handler returns.
+func NewSSE(w http.ResponseWriter, r *http.Request, opts ...SSEOption) (*SSE, error) {
+	cfg := &sseConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	s := &SSE{
+		w:    w,
+		rc:   http.NewResponseController(w),
+		ctx:  r.Context(),
+		stop: make(chan struct{}),
+	}
+	w.Header().Set("Content-Type", ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("flushing response: %w", err)
+	}
+
+	if cfg.heartbeat > 0 {
+		s.wg.Add(1)
+		go s.heartbeat(cfg.heartbeat)
+	}
+	return s, nil
+}
+
+// Send writes the event to the client and flushes it. It returns the request context
+// error if the client has disconnected.
+func (s *SSE) Send(e Event) error {
+	var b bytes.Buffer
+	if e.ID != "" {
+		b.WriteString("id: " + sanitizeField(e.ID) + "\n")
+	}
+	if e.Name != "" {
+		b.WriteString("event: " + sanitizeField(e.Name) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	if e.Data != nil {
+		data, err := eventData(e.Data)
+		if err != nil {
+			return fmt.Errorf("serializing event data: %w", err)
+		}
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return s.write(b.Bytes())
+}
+
+// Comment writes the comment line to the client, comments are ignored by the clients.
+func (s *SSE) Comment(text string) error {
+	return s.write([]byte(": " + sanitizeField(text) + "\n\n"))
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *SSE) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Close stops the heartbeats and prevents any further writes. It is safe to call Close multiple times.
+func (s *SSE) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *SSE) write(b []byte) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSSEClosed
+	}
+	if _, err := s.w.Write(b); err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flushing event: %w", err)
+	}
+	return nil
+}
+
+func (s *SSE) heartbeat(d time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event received by the reconnecting client, or
+// an empty string if the client connects for the first time.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// eventData returns the event payload with the line breaks normalized to "\n".
+func eventData(v any) (string, error) {
+	var data string
+	switch t := v.(type) {
+	case string:
+		data = t
+	case []byte:
+		data = string(t)
+	default:
+		b, err := JSONEncoder{}.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		data = string(b)
+	}
+	return strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data), nil
+}
+
+// sanitizeField removes the line breaks that would break the event framing.
+func sanitizeField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
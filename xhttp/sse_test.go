@@ -0,0 +1,150 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestNewSSE(t *testing.T) {
+	t.Run("should write event stream headers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s, err := NewSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+		if err != nil {
+			t.Fatalf("NewSSE() error = %v", err)
+		}
+		defer s.Close()
+
+		if got := w.Header().Get("Content-Type"); got != ContentTypeEventStream {
+			t.Errorf("NewSSE() = content type got %q, want %q", got, ContentTypeEventStream)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("NewSSE() = cache control got %q, want %q", got, "no-cache")
+		}
+		if !w.Flushed {
+			t.Errorf("NewSSE() = expected headers to be flushed")
+		}
+	})
+
+	t.Run("should return error if writer does not support flushing", func(t *testing.T) {
+		w := &noFlushWriter{ResponseWriter: httptest.NewRecorder()}
+		if _, err := NewSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil)); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("NewSSE() error = %v, want %v", err, http.ErrNotSupported)
+		}
+	})
+}
+
+func TestSSE_Send(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "should write all event fields",
+			event: Event{ID: "42", Name: "status", Retry: 3 * time.Second, Data: "active"},
+			want:  "id: 42\nevent: status\nretry: 3000\ndata: active\n\n",
+		},
+		{
+			name:  "should write multi-line data",
+			event: Event{Data: "line 1\r\nline 2\nline 3"},
+			want:  "data: line 1\ndata: line 2\ndata: line 3\n\n",
+		},
+		{
+			name:  "should serialize data as JSON",
+			event: Event{Data: &dummyResponse{Value: "active"}},
+			want:  "data: {\"value\":\"active\"}\n\n",
+		},
+		{
+			name:  "should remove line breaks from fields",
+			event: Event{ID: "4\n2", Name: "sta\r\ntus"},
+			want:  "id: 42\nevent: status\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s, err := NewSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+			if err != nil {
+				t.Fatalf("NewSSE() error = %v", err)
+			}
+			defer s.Close()
+
+			if err := s.Send(tt.event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("Send() = body got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// flushRecorder is a ResponseRecorder notifying about the flushes of the response.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (r *flushRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	select {
+	case r.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func TestSSE_Heartbeat(t *testing.T) {
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{}, 1)}
+	s, err := NewSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil), Heartbeat(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewSSE() error = %v", err)
+	}
+	<-w.flushed // the header
+	<-w.flushed // the first heartbeat
+	s.Close()
+
+	if got := w.Body.String(); !strings.HasPrefix(got, ": heartbeat\n\n") {
+		t.Errorf("Heartbeat() = body got %q, want heartbeat comments", got)
+	}
+	if err := s.Send(Event{Data: "late"}); !errors.Is(err, errSSEClosed) {
+		t.Errorf("Send() error = %v, want %v", err, errSSEClosed)
+	}
+}
+
+func TestSSE_Disconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	s, err := NewSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx), Heartbeat(time.Hour))
+	if err != nil {
+		t.Fatalf("NewSSE() error = %v", err)
+	}
+	defer s.Close()
+	cancel()
+
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Done() = expected channel to be closed")
+	}
+	if err := s.Send(Event{Data: "late"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "42")
+
+	if got := LastEventID(r); got != "42" {
+		t.Errorf("LastEventID() = got %q, want %q", got, "42")
+	}
+}
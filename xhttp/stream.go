@@ -0,0 +1,151 @@
+package xhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ContentTypeNDJSON is the media type of the newline delimited JSON (JSON Lines) streams.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// StreamOption configures the streaming response writers.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	flushEvery    int
+	flushInterval time.Duration
+}
+
+// FlushEvery sets the number of items after which the written items are flushed to the
+// client, defaults to 100. A value of 1 flushes every item.
+func FlushEvery(n int) StreamOption {
+	return func(c *streamConfig) {
+		c.flushEvery = n
+	}
+}
+
+// FlushInterval sets the interval in which the written items are flushed to the client
+// regardless of their number, defaults to 1 second. A non-positive value disables the
+// interval flushing.
+func FlushInterval(d time.Duration) StreamOption {
+	return func(c *streamConfig) {
+		c.flushInterval = d
+	}
+}
+
+// StreamJSONLines replies to the request with an HTTP 200 StatusOK and the items received
+// from the channel, each serialized using json.Marshal on a separate line as
+// application/x-ndjson stream.
+//
+// Items are flushed to the client using http.Flusher (if supported) in batches, see
+// FlushEvery and FlushInterval. The stream ends when the items channel is closed or when
+// the request context is done; in the latter case the context error is returned and the
+// producer of the items is expected to stop on the same context.
+//
+// Since the response status is already sent, errors are returned to the caller and not
+// written to the client.
+func StreamJSONLines[T any](w http.ResponseWriter, r *http.Request, items <-chan T, opts ...StreamOption) error {
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	return stream(w, r, items, true, opts)
+}
+
+// StreamJSONArray replies to the request with an HTTP 200 StatusOK and the items received
+// from the channel serialized as a single application/json array, for clients that cannot
+// read application/x-ndjson streams.
+//
+// Items are written and flushed the same way as by StreamJSONLines. If the stream is
+// interrupted, the closing bracket is not written, so that clients can detect incomplete
+// responses.
+func StreamJSONArray[T any](w http.ResponseWriter, r *http.Request, items <-chan T, opts ...StreamOption) error {
+	w.Header().Set("Content-Type", "application/json")
+	return stream(w, r, items, false, opts)
+}
+
+// stream writes the items received from the channel either as JSON lines or as a JSON array.
+func stream[T any](w http.ResponseWriter, r *http.Request, items <-chan T, lines bool, opts []StreamOption) error {
+	cfg := &streamConfig{
+		flushEvery:    100,
+		flushInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	flush := func() error {
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return fmt.Errorf("flushing response: %w", err)
+		}
+		return nil
+	}
+
+	var tick <-chan time.Time
+	if cfg.flushInterval > 0 {
+		ticker := time.NewTicker(cfg.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	write := func(b []byte) error {
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("writing response: %w", err)
+		}
+		return nil
+	}
+	if !lines {
+		if err := write([]byte("[")); err != nil {
+			return err
+		}
+	}
+
+	pending, written := 0, 0
+	for {
+		select {
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case <-tick:
+			if pending == 0 {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			pending = 0
+		case item, ok := <-items:
+			if !ok {
+				if !lines {
+					if err := write([]byte("]")); err != nil {
+						return err
+					}
+				}
+				return flush()
+			}
+			b, err := json.Marshal(item)
+			if err != nil {
+				return fmt.Errorf("serializing item %d: %w", written, err)
+			}
+			switch {
+			case lines:
+				b = append(b, '\n')
+			case written > 0:
+				b = append([]byte(","), b...)
+			}
+			if err := write(b); err != nil {
+				return err
+			}
+			written++
+			if pending++; cfg.flushEvery > 0 && pending >= cfg.flushEvery {
+				if err := flush(); err != nil {
+					return err
+				}
+				pending = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package xhttp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+	flushed chan struct{} // notified about the flushes if not nil
+}
+
+func (w *flushCounter) Flush() {
+	w.flushes++
+	w.ResponseRecorder.Flush()
+	select {
+	case w.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func produce[T any](items ...T) <-chan T {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+func TestStreamJSONLines(t *testing.T) {
+	t.Run("should write each item on a separate line", func(t *testing.T) {
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "/export", nil)
+		items := produce(&dummyResponse{Value: "a"}, &dummyResponse{Value: "b"}, &dummyResponse{Value: "c"})
+
+		if err := StreamJSONLines(w, r, items, FlushEvery(2)); err != nil {
+			t.Fatalf("StreamJSONLines() error = %v", err)
+		}
+		want := "{\"value\":\"a\"}\n{\"value\":\"b\"}\n{\"value\":\"c\"}\n"
+		if got := w.Body.String(); got != want {
+			t.Errorf("StreamJSONLines() = body got %q, want %q", got, want)
+		}
+		if got := w.Header().Get("Content-Type"); got != ContentTypeNDJSON {
+			t.Errorf("StreamJSONLines() = content type got %q, want %q", got, ContentTypeNDJSON)
+		}
+		// one flush after the first batch and one at the end of the stream
+		if got := w.flushes; got != 2 {
+			t.Errorf("StreamJSONLines() = flushes got %d, want %d", got, 2)
+		}
+	})
+
+	t.Run("should flush pending items on interval", func(t *testing.T) {
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{}, 1)}
+		r := httptest.NewRequest(http.MethodGet, "/export", nil)
+		items := make(chan int)
+		go func() {
+			defer close(items)
+			items <- 1
+			<-w.flushed
+		}()
+
+		if err := StreamJSONLines(w, r, items, FlushInterval(10*time.Millisecond)); err != nil {
+			t.Fatalf("StreamJSONLines() error = %v", err)
+		}
+		if got := w.flushes; got != 2 {
+			t.Errorf("StreamJSONLines() = flushes got %d, want %d", got, 2)
+		}
+	})
+
+	t.Run("should stop when request context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+		items := make(chan int)
+		go func() {
+			items <- 1
+			cancel()
+		}()
+
+		if err := StreamJSONLines(w, r, items); !errors.Is(err, context.Canceled) {
+			t.Errorf("StreamJSONLines() error = %v, want %v", err, context.Canceled)
+		}
+		if got := w.Body.String(); got != "1\n" {
+			t.Errorf("StreamJSONLines() = body got %q, want %q", got, "1\n")
+		}
+	})
+
+	t.Run("should return error if item serialization fails", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/export", nil)
+
+		err := StreamJSONLines(w, r, produce(1.5, math.NaN()))
+		want := "serializing item 1: json: unsupported value: NaN"
+		if err == nil || err.Error() != want {
+			t.Errorf("StreamJSONLines() error = %v, want %v", err, want)
+		}
+	})
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	tests := []struct {
+		name  string
+		items <-chan string
+		want  string
+	}{
+		{name: "should write items as array", items: produce("a", "b", "c"), want: `["a","b","c"]`},
+		{name: "should write empty array", items: produce[string](), want: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/export", nil)
+
+			if err := StreamJSONArray(w, r, tt.items); err != nil {
+				t.Fatalf("StreamJSONArray() error = %v", err)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("StreamJSONArray() = body got %s, want %s", got, tt.want)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("StreamJSONArray() = content type got %q, want %q", got, "application/json")
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package xvalidate
+
+import (
+	"errors"
+	"fmt"
+)
+
+type createListing struct {
+	Title    string   `json:"title" validate:"required,max=100"`
+	Rooms    int      `json:"rooms" validate:"min=1,max=10"`
+	Currency string   `json:"currency" validate:"oneof=EUR PLN"`
+	Photos   []string `json:"photos" validate:"max=20,dive,url"`
+	MinPrice int      `json:"minPrice"`
+	MaxPrice int      `json:"maxPrice"`
+}
+
+func (l *createListing) Validate() error {
+	if l.MaxPrice < l.MinPrice {
+		return &FieldError{Field: "maxPrice", Rule: "range", Message: "must not be lower than minPrice"}
+	}
+	return nil
+}
+
+func ExampleValidate() {
+	l := createListing{
+		Rooms:    0,
+		Currency: "USD",
+		Photos:   []string{"https://example.com/1.jpg", "2.jpg"},
+		MinPrice: 100,
+		MaxPrice: 50,
+	}
+
+	err := Validate(&l)
+
+	var errs Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			fmt.Println(e)
+		}
+	}
+	// Output:
+	// title: is required
+	// rooms: must be at least 1
+	// currency: must be one of: EUR, PLN
+	// photos[1]: must be a valid URL
+	// maxPrice: must not be lower than minPrice
+}
@@ -0,0 +1,289 @@
+package xvalidate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldRules are the parsed rules of a `validate` struct tag.
+type fieldRules struct {
+	required  bool
+	omitempty bool
+	rules     []rule
+	// dive holds the rules of the items of a slice or the values of a map.
+	dive *fieldRules
+}
+
+// rule is a single validation rule.
+type rule struct {
+	name string
+	// check returns the message describing why v is invalid, or an empty string if v is valid.
+	// It returns an error if the rule does not support the type of v.
+	check func(v reflect.Value) (string, error)
+}
+
+var errUnsupportedType = errors.New("unsupported type")
+
+// ruleFuncs are the constructors of the check functions by the rule names.
+var ruleFuncs = map[string]func(param string) (func(v reflect.Value) (string, error), error){
+	"min":    minRule,
+	"max":    maxRule,
+	"len":    lenRule,
+	"oneof":  oneOfRule,
+	"regexp": regexpRule,
+	"email":  emailRule,
+	"url":    urlRule,
+}
+
+// Tag is a parsed `validate` struct tag, see ParseTag.
+type Tag struct {
+	// Required reports whether the tag has the required rule.
+	Required bool
+	// OmitEmpty reports whether the tag has the omitempty rule.
+	OmitEmpty bool
+	// Rules are the other rules in the order of the tag, e.g. {Name: "min", Param: "3"}.
+	Rules []Rule
+	// Dive holds the rules following the dive rule, i.e. of the items of a slice or the
+	// values of a map, or nil if the tag has no dive rule.
+	Dive *Tag
+}
+
+// Rule is a single rule of a `validate` struct tag.
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// ParseTag parses the `validate` struct tag, e.g. to translate the rules into a schema. The
+// rules are separated by commas, except for the regexp rule, which must be the last one of
+// the tag so that its pattern can contain commas. It returns nil for an empty tag and an
+// error for an unknown rule. The parameters of the rules are checked by Validate.
+func ParseTag(tag string) (*Tag, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	root := &Tag{}
+	current := root
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regexp=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			current.Required = true
+		case "omitempty":
+			current.OmitEmpty = true
+		case "dive":
+			current.Dive = &Tag{}
+			current = current.Dive
+		default:
+			if _, ok := ruleFuncs[name]; !ok {
+				return nil, fmt.Errorf("unknown rule %q", name)
+			}
+			current.Rules = append(current.Rules, Rule{Name: name, Param: param})
+		}
+	}
+	return root, nil
+}
+
+// parseRules parses the rules of the tag, it returns nil for an empty tag.
+func parseRules(tag string) (*fieldRules, error) {
+	t, err := ParseTag(tag)
+	if t == nil || err != nil {
+		return nil, err
+	}
+	return compileRules(t)
+}
+
+// compileRules returns the rules with the check functions of the parsed tag.
+func compileRules(t *Tag) (*fieldRules, error) {
+	fr := &fieldRules{required: t.Required, omitempty: t.OmitEmpty}
+	for _, r := range t.Rules {
+		check, err := ruleFuncs[r.Name](r.Param)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		fr.rules = append(fr.rules, rule{name: r.Name, check: check})
+	}
+	if t.Dive != nil {
+		dive, err := compileRules(t.Dive)
+		if err != nil {
+			return nil, err
+		}
+		fr.dive = dive
+	}
+	return fr, nil
+}
+
+func minRule(param string) (func(v reflect.Value) (string, error), error) {
+	return sizeRule(param, func(size, n float64) bool { return size >= n }, "at least")
+}
+
+func maxRule(param string) (func(v reflect.Value) (string, error), error) {
+	return sizeRule(param, func(size, n float64) bool { return size <= n }, "at most")
+}
+
+// sizeRule returns the check comparing the number, length of a string or number of items
+// of a slice or map with param using cmp.
+func sizeRule(param string, cmp func(size, n float64) bool, desc string) (func(v reflect.Value) (string, error), error) {
+	n, numErr := strconv.ParseFloat(param, 64)
+	d, durErr := time.ParseDuration(param)
+	if numErr != nil && durErr != nil {
+		return nil, fmt.Errorf("invalid parameter %q", param)
+	}
+	return func(v reflect.Value) (string, error) {
+		if v.Type() == durationType {
+			if durErr != nil {
+				return "", fmt.Errorf("invalid duration parameter %q", param)
+			}
+			if !cmp(float64(v.Int()), float64(d)) {
+				return fmt.Sprintf("must be %s %s", desc, d), nil
+			}
+			return "", nil
+		}
+		if numErr != nil {
+			return "", fmt.Errorf("invalid number parameter %q", param)
+		}
+		if number, ok := toFloat(v); ok {
+			if !cmp(number, n) {
+				return fmt.Sprintf("must be %s %s", desc, param), nil
+			}
+			return "", nil
+		}
+		size, unit, err := length(v)
+		if err != nil {
+			return "", err
+		}
+		if !cmp(float64(size), n) {
+			return sizeMessage(desc, param, unit), nil
+		}
+		return "", nil
+	}, nil
+}
+
+func lenRule(param string) (func(v reflect.Value) (string, error), error) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter %q", param)
+	}
+	return func(v reflect.Value) (string, error) {
+		size, unit, err := length(v)
+		if err != nil {
+			return "", err
+		}
+		if size != n {
+			return sizeMessage("exactly", param, unit), nil
+		}
+		return "", nil
+	}, nil
+}
+
+func oneOfRule(param string) (func(v reflect.Value) (string, error), error) {
+	values := strings.Fields(param)
+	if len(values) == 0 {
+		return nil, errors.New("missing values")
+	}
+	msg := "must be one of: " + strings.Join(values, ", ")
+	return func(v reflect.Value) (string, error) {
+		var s string
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = strconv.FormatUint(v.Uint(), 10)
+		default:
+			return "", errUnsupportedType
+		}
+		for _, value := range values {
+			if s == value {
+				return "", nil
+			}
+		}
+		return msg, nil
+	}, nil
+}
+
+func regexpRule(param string) (func(v reflect.Value) (string, error), error) {
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return nil, err
+	}
+	return stringRule(func(s string) bool { return re.MatchString(s) }, "must match the pattern "+param), nil
+}
+
+func emailRule(string) (func(v reflect.Value) (string, error), error) {
+	return stringRule(func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	}, "must be a valid email address"), nil
+}
+
+func urlRule(string) (func(v reflect.Value) (string, error), error) {
+	return stringRule(func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}, "must be a valid URL"), nil
+}
+
+// stringRule returns the check of the string values using valid.
+func stringRule(valid func(s string) bool, msg string) func(v reflect.Value) (string, error) {
+	return func(v reflect.Value) (string, error) {
+		if v.Kind() != reflect.String {
+			return "", errUnsupportedType
+		}
+		if !valid(v.String()) {
+			return msg, nil
+		}
+		return "", nil
+	}
+}
+
+// toFloat returns the number value of v, or false if v is not a number.
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// length returns the number of characters of a string or the number of items of a slice,
+// array or map, along with the unit name.
+func length(v reflect.Value) (int, string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), "characters", nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), "items", nil
+	}
+	return 0, "", errUnsupportedType
+}
+
+func sizeMessage(desc, param, unit string) string {
+	if unit == "characters" {
+		return fmt.Sprintf("must be %s %s characters long", desc, param)
+	}
+	return fmt.Sprintf("must contain %s %s items", desc, param)
+}
@@ -0,0 +1,93 @@
+package xvalidate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	t.Run("should parse nested dive rules", func(t *testing.T) {
+		rules, err := parseRules("required,max=2,dive,omitempty,dive,min=1")
+		if err != nil {
+			t.Fatalf("parseRules() error = %v", err)
+		}
+		if !rules.required || len(rules.rules) != 1 || rules.rules[0].name != "max" {
+			t.Errorf("parseRules() = unexpected root rules %+v", rules)
+		}
+		if rules.dive == nil || !rules.dive.omitempty || rules.dive.dive == nil || rules.dive.dive.rules[0].name != "min" {
+			t.Errorf("parseRules() = unexpected dive rules %+v", rules.dive)
+		}
+	})
+
+	t.Run("should keep commas of regexp pattern", func(t *testing.T) {
+		rules, err := parseRules("required,regexp=^a{1,3}$")
+		if err != nil {
+			t.Fatalf("parseRules() error = %v", err)
+		}
+		if msg, _ := rules.rules[0].check(reflect.ValueOf("aaa")); msg != "" {
+			t.Errorf("check() = got %q, want valid", msg)
+		}
+	})
+}
+
+func TestParseTag(t *testing.T) {
+	t.Run("should parse rules and params", func(t *testing.T) {
+		got, err := ParseTag("required,min=3,dive,oneof=a b,regexp=^[a-z]{1,3}$")
+		if err != nil {
+			t.Fatalf("ParseTag() error = %v", err)
+		}
+		want := &Tag{
+			Required: true,
+			Rules:    []Rule{{Name: "min", Param: "3"}},
+			Dive:     &Tag{Rules: []Rule{{Name: "oneof", Param: "a b"}, {Name: "regexp", Param: "^[a-z]{1,3}$"}}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseTag() = got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("should return nil for empty tag", func(t *testing.T) {
+		if got, err := ParseTag(""); got != nil || err != nil {
+			t.Errorf("ParseTag() = got %+v, %v, want nil", got, err)
+		}
+	})
+
+	t.Run("should return error for unknown rule", func(t *testing.T) {
+		if _, err := ParseTag("required,positive"); err == nil {
+			t.Errorf("ParseTag() error = nil, want error")
+		}
+	})
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value any
+		valid bool
+	}{
+		{tag: "email", value: "user@example.com", valid: true},
+		{tag: "email", value: "User <user@example.com>", valid: false},
+		{tag: "email", value: "user@", valid: false},
+		{tag: "url", value: "https://example.com/listings?id=1", valid: true},
+		{tag: "url", value: "/listings", valid: false},
+		{tag: "oneof=1 2", value: uint(2), valid: true},
+		{tag: "oneof=1 2", value: 3, valid: false},
+		{tag: "len=2", value: "żó", valid: true},
+		{tag: "len=2", value: map[string]int{"a": 1}, valid: false},
+		{tag: "min=0.5", value: 0.4, valid: false},
+		{tag: "max=2", value: [2]int{}, valid: true},
+	}
+	for _, tt := range tests {
+		rules, err := parseRules(tt.tag)
+		if err != nil {
+			t.Fatalf("parseRules(%q) error = %v", tt.tag, err)
+		}
+		msg, err := rules.rules[0].check(reflect.ValueOf(tt.value))
+		if err != nil {
+			t.Errorf("check(%q, %v) error = %v", tt.tag, tt.value, err)
+		}
+		if got := msg == ""; got != tt.valid {
+			t.Errorf("check(%q, %v) = got valid %v, want %v", tt.tag, tt.value, got, tt.valid)
+		}
+	}
+}
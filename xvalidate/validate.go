@@ -0,0 +1,333 @@
+// Package xvalidate provides validation of structs driven by the `validate` struct tags.
+package xvalidate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldError describes a single invalid field.
+type FieldError struct {
+	// Field is the path of the field using the JSON names, e.g. "address.rooms[0]".
+	Field string `json:"field"`
+	// Rule is the name of the failed rule, e.g. "required", or "validate" for the errors
+	// returned by the Validate method.
+	Rule string `json:"rule"`
+	// Message is the human-readable description of the error, e.g. "is required".
+	Message string `json:"message"`
+}
+
+// Error implements error interface.
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Errors is the list of the invalid fields returned by Validate.
+type Errors []*FieldError
+
+// Error implements error interface.
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual FieldError values, it is used by errors.Is and errors.As.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Validator is implemented by the types with custom validation logic. The Validate method
+// is called after the validation of the struct tags, for the validated value and any nested
+// struct. A returned FieldError or Errors is reported relative to the path of the value, any
+// other error is reported as the error of the value itself.
+type Validator interface {
+	Validate() error
+}
+
+// Option configures the validation.
+type Option func(*config)
+
+type config struct {
+	nameTags []string
+}
+
+// WithNameTags sets the struct tags used to resolve the names of the fields in the error
+// paths, defaults to "json". The first tag present on a field is used, fields without any
+// of the tags use the Go field name.
+func WithNameTags(tags ...string) Option {
+	return func(c *config) {
+		c.nameTags = tags
+	}
+}
+
+// Validate validates v, usually a struct or a pointer to a struct, using the rules of the
+// `validate` struct tags of its fields, e.g. `validate:"required,max=100"`. Nested structs,
+// including the structs in slices and maps, are validated recursively. It returns Errors if
+// any field is invalid.
+//
+// The rules of a tag are separated by commas:
+//   - required: the value must not be zero, e.g. nil pointer, empty string or slice.
+//   - omitempty: the remaining rules are skipped for the zero value.
+//   - min=n, max=n: the minimum and maximum number, length of a string or number of items
+//     of a slice or map. Durations use the duration format, e.g. min=1s.
+//   - len=n: the exact length of a string or number of items of a slice or map.
+//   - oneof=a b c: the value must be one of the space-separated values.
+//   - regexp=pattern: the string must match the pattern. It must be the last rule, since the
+//     pattern may contain commas.
+//   - email: the string must be an email address, e.g. "user@example.com".
+//   - url: the string must be an absolute URL.
+//   - dive: the rules following dive are applied to the items of a slice or the values of a map.
+//
+// Rules other than required are not applied to nil pointers, so pointers can be used for the
+// optional values. Types implementing Validator are additionally validated by their Validate
+// method.
+//
+// Invalid tags are reported as an error other than Errors.
+func Validate(v any, opts ...Option) error {
+	cfg := &config{nameTags: []string{"json"}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() != reflect.Pointer {
+		// copy the value so that the Validate methods with pointer receivers can be called
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+
+	vd := &validator{cfg: cfg}
+	if err := vd.value(rv, "", nil); err != nil {
+		return err
+	}
+	if len(vd.errs) == 0 {
+		return nil
+	}
+	return vd.errs
+}
+
+type validator struct {
+	cfg  *config
+	errs Errors
+}
+
+// value validates v using the supplied rules and walks its nested values.
+func (vd *validator) value(v reflect.Value, path string, rules *fieldRules) error {
+	if rules != nil {
+		if v.IsZero() {
+			if rules.required {
+				vd.errs = append(vd.errs, &FieldError{Field: path, Rule: "required", Message: "is required"})
+				return nil
+			}
+			if rules.omitempty {
+				return nil
+			}
+		}
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if rules != nil {
+		for _, r := range rules.rules {
+			msg, err := r.check(v)
+			if err != nil {
+				return fmt.Errorf("xvalidate: field %q: rule %q: %w", path, r.name, err)
+			}
+			if msg != "" {
+				vd.errs = append(vd.errs, &FieldError{Field: path, Rule: r.name, Message: msg})
+				return nil
+			}
+		}
+	}
+
+	var dive *fieldRules
+	if rules != nil {
+		dive = rules.dive
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return vd.structValue(v, path)
+	case reflect.Slice, reflect.Array:
+		if dive == nil && !hasNested(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := vd.value(v.Index(i), fmt.Sprintf("%s[%d]", path, i), dive); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if dive == nil && !hasNested(v.Type().Elem()) {
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			// map values are not addressable, so they are copied
+			item := reflect.New(v.Type().Elem()).Elem()
+			item.Set(v.MapIndex(key))
+			if err := vd.value(item, fmt.Sprintf("%s[%v]", path, key), dive); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structValue validates the fields of the struct v and calls its Validate method.
+func (vd *validator) structValue(v reflect.Value, path string) error {
+	fields, err := structFields(v.Type(), vd.cfg.nameTags)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if f.embedded {
+			if err := vd.value(fv, path, f.rules); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := vd.value(fv, joinPath(path, f.name), f.rules); err != nil {
+			return err
+		}
+	}
+
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	validator, ok := v.Addr().Interface().(Validator)
+	if !ok {
+		return nil
+	}
+	err = validator.Validate()
+	if err == nil {
+		return nil
+	}
+	var errs Errors
+	var fe *FieldError
+	switch {
+	case errors.As(err, &errs):
+		for _, e := range errs {
+			vd.errs = append(vd.errs, &FieldError{Field: joinPath(path, e.Field), Rule: e.Rule, Message: e.Message})
+		}
+	case errors.As(err, &fe):
+		vd.errs = append(vd.errs, &FieldError{Field: joinPath(path, fe.Field), Rule: fe.Rule, Message: fe.Message})
+	default:
+		vd.errs = append(vd.errs, &FieldError{Field: path, Rule: "validate", Message: err.Error()})
+	}
+	return nil
+}
+
+type structField struct {
+	index    int
+	name     string
+	embedded bool
+	rules    *fieldRules
+}
+
+type cacheKey struct {
+	typ      reflect.Type
+	nameTags string
+}
+
+type cacheEntry struct {
+	fields []structField
+	err    error
+}
+
+var fieldsCache sync.Map // cacheKey -> cacheEntry
+
+// structFields returns the validated fields of the struct type typ.
+func structFields(typ reflect.Type, nameTags []string) ([]structField, error) {
+	key := cacheKey{typ: typ, nameTags: strings.Join(nameTags, ",")}
+	if e, ok := fieldsCache.Load(key); ok {
+		return e.(cacheEntry).fields, e.(cacheEntry).err
+	}
+
+	var fields []structField
+	var err error
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		rules, perr := parseRules(tag)
+		if perr != nil {
+			err = fmt.Errorf("xvalidate: field %s.%s: %w", typ, field.Name, perr)
+			break
+		}
+		name := fieldName(field, nameTags)
+		embedded := field.Anonymous && name == ""
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		if rules == nil && !hasNested(field.Type) {
+			continue
+		}
+		fields = append(fields, structField{index: i, name: name, embedded: embedded, rules: rules})
+	}
+	fieldsCache.Store(key, cacheEntry{fields: fields, err: err})
+	return fields, err
+}
+
+// fieldName returns the name of the field from the first of the tags present on the field.
+func fieldName(field reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		if value, ok := field.Tag.Lookup(tag); ok {
+			name, _, _ := strings.Cut(value, ",")
+			return name
+		}
+	}
+	return ""
+}
+
+// hasNested reports whether the values of typ may contain structs to validate.
+func hasNested(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return hasNested(typ.Elem())
+	case reflect.Struct, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// joinPath returns the path of the field name nested in the value of the supplied path.
+func joinPath(path, name string) string {
+	switch {
+	case path == "":
+		return name
+	case name == "":
+		return path
+	case strings.HasPrefix(name, "["):
+		return path + name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,217 @@
+package xvalidate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City   string `json:"city" validate:"required"`
+	Street string `json:"street,omitempty" validate:"omitempty,min=3"`
+}
+
+type contact struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type listing struct {
+	ID       string            `json:"id" validate:"required,len=8"`
+	Title    string            `json:"title" validate:"required,min=3,max=20"`
+	Price    *float64          `json:"price,omitempty" validate:"min=1"`
+	Rooms    int               `json:"rooms" validate:"min=1,max=10"`
+	Sort     string            `json:"sort,omitempty" validate:"omitempty,oneof=asc desc"`
+	Website  string            `json:"website,omitempty" validate:"omitempty,url"`
+	Code     string            `json:"code" validate:"regexp=^[A-Z]{2},[0-9]+$"`
+	Tags     []string          `json:"tags" validate:"max=3,dive,required,max=5"`
+	Labels   map[string]string `json:"labels" validate:"dive,oneof=new hot"`
+	TTL      time.Duration     `json:"ttl" validate:"min=1s"`
+	Address  address           `json:"address"`
+	Contacts []*contact        `json:"contacts" validate:"required"`
+	Internal string            `json:"-" validate:"required"`
+	Skipped  string            `json:"skipped" validate:"-"`
+}
+
+func validListing() listing {
+	return listing{
+		ID:       "abcd1234",
+		Title:    "flat",
+		Rooms:    2,
+		Code:     "PL,12",
+		Tags:     []string{"new"},
+		Labels:   map[string]string{"a": "hot"},
+		TTL:      time.Minute,
+		Address:  address{City: "Warsaw"},
+		Contacts: []*contact{{Email: "user@example.com"}},
+		Internal: "x",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	price := 0.5
+
+	tests := []struct {
+		name   string
+		modify func(l *listing)
+		want   Errors
+	}{
+		{
+			name:   "should return nil for valid struct",
+			modify: func(l *listing) {},
+		},
+		{
+			name: "should return errors of all invalid fields",
+			modify: func(l *listing) {
+				l.ID = ""
+				l.Title = "ab"
+				l.Price = &price
+				l.Rooms = 11
+				l.Sort = "random"
+				l.Website = "example.com"
+				l.Code = "pl"
+				l.TTL = time.Millisecond
+				l.Contacts = nil
+				l.Internal = ""
+			},
+			want: Errors{
+				{Field: "id", Rule: "required", Message: "is required"},
+				{Field: "title", Rule: "min", Message: "must be at least 3 characters long"},
+				{Field: "price", Rule: "min", Message: "must be at least 1"},
+				{Field: "rooms", Rule: "max", Message: "must be at most 10"},
+				{Field: "sort", Rule: "oneof", Message: "must be one of: asc, desc"},
+				{Field: "website", Rule: "url", Message: "must be a valid URL"},
+				{Field: "code", Rule: "regexp", Message: "must match the pattern ^[A-Z]{2},[0-9]+$"},
+				{Field: "ttl", Rule: "min", Message: "must be at least 1s"},
+				{Field: "contacts", Rule: "required", Message: "is required"},
+				{Field: "Internal", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name: "should validate nested structs, slices and maps",
+			modify: func(l *listing) {
+				l.Tags = []string{"new", "", "too-long"}
+				l.Labels = map[string]string{"b": "old", "a": "hot"}
+				l.Address = address{Street: "a"}
+				l.Contacts = []*contact{{Email: "user@example.com"}, nil, {Email: "user"}}
+			},
+			want: Errors{
+				{Field: "tags[1]", Rule: "required", Message: "is required"},
+				{Field: "tags[2]", Rule: "max", Message: "must be at most 5 characters long"},
+				{Field: "labels[b]", Rule: "oneof", Message: "must be one of: new, hot"},
+				{Field: "address.city", Rule: "required", Message: "is required"},
+				{Field: "address.street", Rule: "min", Message: "must be at least 3 characters long"},
+				{Field: "contacts[2].email", Rule: "email", Message: "must be a valid email address"},
+			},
+		},
+		{
+			name: "should validate number of items",
+			modify: func(l *listing) {
+				l.Tags = []string{"a", "b", "c", "d"}
+			},
+			want: Errors{
+				{Field: "tags", Rule: "max", Message: "must contain at most 3 items"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := validListing()
+			tt.modify(&l)
+
+			err := Validate(&l)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var got Errors
+			if !errors.As(err, &got) {
+				t.Fatalf("Validate() error = %v, want Errors", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type booking struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Guest guest     `json:"guest"`
+}
+
+func (b *booking) Validate() error {
+	if !b.To.After(b.From) {
+		return &FieldError{Field: "to", Rule: "after", Message: "must be after from"}
+	}
+	return nil
+}
+
+type guest struct {
+	Name string `json:"name"`
+}
+
+func (g guest) Validate() error {
+	if g.Name == "" {
+		return errors.New("guest name is missing")
+	}
+	return nil
+}
+
+func TestValidate_Validator(t *testing.T) {
+	now := time.Now()
+	b := booking{From: now, To: now}
+
+	// passed by value, so that the pointer receiver requires a copy
+	err := Validate(b)
+
+	want := Errors{
+		{Field: "guest", Rule: "validate", Message: "guest name is missing"},
+		{Field: "to", Rule: "after", Message: "must be after from"},
+	}
+	if got := Errors(nil); !errors.As(err, &got) || !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = got %v, want %v", err, want)
+	}
+}
+
+func TestValidate_NameTag(t *testing.T) {
+	type query struct {
+		ID       string `path:"id" validate:"len=3"`
+		PriceMin int    `query:"price_min" json:"priceMin" validate:"min=0"`
+	}
+
+	err := Validate(query{ID: "1", PriceMin: -1}, WithNameTags("query", "path"))
+
+	if want := "id: must be exactly 3 characters long; price_min: must be at least 0"; err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want %v", err, want)
+	}
+}
+
+func TestValidate_InvalidTag(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "should fail on unknown rule", v: struct {
+			A string `validate:"unknown"`
+		}{}},
+		{name: "should fail on invalid parameter", v: struct {
+			A string `validate:"min=x"`
+		}{}},
+		{name: "should fail on unsupported type", v: struct {
+			A bool `validate:"min=1"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v)
+			var errs Errors
+			if err == nil || errors.As(err, &errs) {
+				t.Errorf("Validate() error = %v, want invalid tag error", err)
+			}
+		})
+	}
+}